@@ -0,0 +1,119 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// When the configuration file has API keys, the serve command serves only
+// the requests with one of them, either as a bearer token or, for feed
+// readers, in the key parameter of the URL. Each key may have saved
+// searches of its own, besides the shared ones, and a rate limit:
+//
+//	"keys": [
+//		{"name": "ana", "key": "0123456789abcdef", "rate": "60/m",
+//		 "searches": [{"name": "go", "pattern": "(?i)\\bgo\\b"}]}
+//	]
+
+// An apiKey gives a member of a team access to the serve command.
+type apiKey struct {
+	Name     string        `json:"name"`
+	Key      string        `json:"key"`
+	Rate     string        `json:"rate,omitempty"`     // requests allowed, such as 60/m; -rate-limit by default
+	Searches []savedSearch `json:"searches,omitempty"` // of this key only
+
+	rate rate
+}
+
+// check checks k and parses its rate.
+func (k *apiKey) check() error {
+	if k.Name == "" || k.Key == "" {
+		return fmt.Errorf("every key needs a name and a key")
+	}
+	if k.Rate != "" {
+		var err error
+		if k.rate, err = parseRate(k.Rate); err != nil {
+			return fmt.Errorf("key %q: %v", k.Name, err)
+		}
+	}
+	for _, s := range k.Searches {
+		if err := s.check(); err != nil {
+			return fmt.Errorf("key %q: %v", k.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkKeys checks the keys of c and that their names and keys are unique.
+func (c *config) checkKeys() error {
+	names := make(map[string]bool)
+	keys := make(map[string]bool)
+	for i := range c.Keys {
+		k := &c.Keys[i]
+		if err := k.check(); err != nil {
+			return err
+		}
+		if names[k.Name] || keys[k.Key] {
+			return fmt.Errorf("key %q: duplicate name or key", k.Name)
+		}
+		names[k.Name], keys[k.Key] = true, true
+	}
+	return nil
+}
+
+// keyContext is the context key of the apiKey of a request.
+type keyContext struct{}
+
+// requestKey returns the API key r was sent with, or nil.
+func requestKey(r *http.Request) *apiKey {
+	k, _ := r.Context().Value(keyContext{}).(*apiKey)
+	return k
+}
+
+// authenticate wraps h so that, if there are API keys, it only serves
+// the requests with one of them.
+func authenticate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := cfg()
+		if len(c.Keys) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			given = r.URL.Query().Get("key")
+		}
+		i := slices.IndexFunc(c.Keys, func(k apiKey) bool {
+			return subtle.ConstantTimeCompare([]byte(k.Key), []byte(given)) == 1
+		})
+		if given == "" || i < 0 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="news"`)
+			http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyContext{}, &c.Keys[i])))
+	})
+}
+
+// searches returns the saved searches available to the request r: those
+// of its key, which take precedence, and the shared ones.
+func searches(r *http.Request) []savedSearch {
+	c := cfg()
+	if k := requestKey(r); k != nil {
+		return append(slices.Clip(k.Searches), c.Searches...)
+	}
+	return c.Searches
+}
+
+// requestSearch returns the saved search called name available to r.
+func requestSearch(r *http.Request, name string) (savedSearch, error) {
+	return (&config{Searches: searches(r)}).search(name)
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// archiveClient is used for the Wayback Machine, whose Save Page Now
+// service may take a long while to capture a page.
+var archiveClient = &http.Client{Timeout: 2 * time.Minute}
+
+// maxArchiveRequests bounds the concurrent requests to the Wayback
+// Machine, which throttles clients that make too many.
+const maxArchiveRequests = 4
+
+// addArchiveLinks sets the Archive field of the items of r that link
+// somewhere to a Wayback Machine snapshot of their URL, asking for a
+// capture if there is none yet. Failures are logged and leave the field
+// empty.
+func addArchiveLinks(r *searchResult) {
+	sem := make(chan struct{}, maxArchiveRequests)
+	var wg sync.WaitGroup
+	for i := range r.Items {
+		it := &r.Items[i]
+		if it.URL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			link, err := archiveLink(it.URL)
+			if err != nil {
+				noteError("archive", it.ID, fmt.Errorf("%s: %w", it.URL, err))
+				return
+			}
+			it.Archive = link
+		}()
+	}
+	wg.Wait()
+}
+
+// archiveLink returns the address of the most recent Wayback Machine
+// snapshot of rawURL, capturing one first if none exists.
+func archiveLink(rawURL string) (string, error) {
+	resp, err := archiveClient.Get("https://archive.org/wayback/available?url=" + url.QueryEscape(rawURL))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("availability: %s", resp.Status)
+	}
+	var avail struct {
+		Snapshots struct {
+			Closest struct {
+				Available bool   `json:"available"`
+				URL       string `json:"url"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&avail); err != nil {
+		return "", fmt.Errorf("availability: %v", err)
+	}
+	if c := avail.Snapshots.Closest; c.Available && c.URL != "" {
+		return c.URL, nil
+	}
+
+	save, err := archiveClient.Get("https://web.archive.org/save/" + rawURL)
+	if err != nil {
+		return "", err
+	}
+	save.Body.Close()
+	if save.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("save: %s", save.Status)
+	}
+	// The unqualified form redirects to the latest snapshot, which is the
+	// one just taken.
+	return "https://web.archive.org/web/" + rawURL, nil
+}
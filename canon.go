@@ -0,0 +1,109 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shorteners are the hosts of URL shortening and link tracking services,
+// whose links are resolved with -resolve-urls.
+var shorteners = map[string]bool{
+	"bit.ly": true, "buff.ly": true, "dlvr.it": true, "goo.gl": true,
+	"is.gd": true, "lnkd.in": true, "ow.ly": true, "rebrand.ly": true,
+	"shorturl.at": true, "t.co": true, "t.ly": true, "tinyurl.com": true,
+	"trib.al": true, "amzn.to": true, "fb.me": true, "feedproxy.google.com": true,
+}
+
+// trackingParams are query parameters that only identify where a visitor
+// came from. Parameters starting with "utm_" are dropped as well.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true,
+	"mc_cid": true, "mc_eid": true, "igshid": true, "ref_src": true,
+	"_hsenc": true, "_hsmi": true, "mkt_tok": true, "yclid": true,
+}
+
+// resolveClient follows redirects of shortened links.
+var resolveClient = &http.Client{Timeout: 10 * time.Second}
+
+// resolveURLs replaces the URLs of the items of r with their canonical
+// form, after following the redirects of at most limit shortened links.
+func resolveURLs(r *searchResult, limit int) {
+	sem := make(chan struct{}, 8)
+	var wg sync.WaitGroup
+	for i := range r.Items {
+		it := &r.Items[i]
+		if it.URL == "" {
+			continue
+		}
+		if u, err := url.Parse(it.URL); err == nil && shorteners[strings.ToLower(u.Hostname())] && limit > 0 {
+			limit--
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				it.URL = canonicalURL(resolve(it.URL))
+			}()
+			continue
+		}
+		it.URL = canonicalURL(it.URL)
+	}
+	wg.Wait()
+}
+
+// resolve returns the URL that rawURL finally redirects to, or rawURL
+// itself if that cannot be found out.
+func resolve(rawURL string) string {
+	resp, err := resolveClient.Head(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	resp.Body.Close()
+	// Even if the target refuses HEAD, the redirects were followed.
+	return resp.Request.URL.String()
+}
+
+// canonicalURL normalizes rawURL: the scheme and host are lower-cased,
+// default ports, fragments and tracking parameters are removed, and the
+// remaining query parameters are sorted. Invalid URLs are returned as is.
+func canonicalURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"): // an IPv6 literal
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+	u.Fragment, u.RawFragment = "", ""
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if trackingParams[strings.ToLower(k)] || strings.HasPrefix(strings.ToLower(k), "utm_") {
+				q.Del(k)
+			}
+		}
+		u.RawQuery = q.Encode() // sorted by key
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
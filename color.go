@@ -0,0 +1,74 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// A theme holds the SGR parameters used to color each part of the
+// plain-text output. An empty string leaves that part uncolored.
+type theme struct {
+	score, author, time, match string
+}
+
+var themes = map[string]theme{
+	"dark":  {score: "33", author: "36", time: "90", match: "1;31"},
+	"light": {score: "34", author: "35", time: "2", match: "1;31"},
+}
+
+// A painter colors text with a theme, or does nothing if it is off.
+type painter struct {
+	theme
+	on bool
+}
+
+// newPainter returns the painter for output written to w, according to
+// the -color and -theme flags and the NO_COLOR convention
+// (https://no-color.org). With -color auto, only output to a terminal is
+// colored.
+func newPainter(w io.Writer) painter {
+	p := painter{theme: themes[*themeName]}
+	switch *colorMode {
+	case "always":
+		p.on = true
+	case "auto":
+		f, ok := w.(*os.File)
+		p.on = ok && os.Getenv("NO_COLOR") == "" && isTerminal(f)
+	}
+	return p
+}
+
+// paint wraps s in the escape sequences for the SGR parameters sgr.
+func (p painter) paint(sgr, s string) string {
+	if !p.on || sgr == "" || s == "" {
+		return s
+	}
+	return "\x1b[" + sgr + "m" + s + "\x1b[0m"
+}
+
+// highlight paints the matches of re in s.
+func (p painter) highlight(re *pattern, s string) string {
+	if !p.on || re == nil || re.Regexp == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringIndex(s, -1) {
+		b.WriteString(s[last:m[0]])
+		b.WriteString(p.paint(p.match, s[m[0]:m[1]]))
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
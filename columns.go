@@ -0,0 +1,237 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A field is a value computed from each match for -columns and -format:
+// NAME=EXPR, or just EXPR, where EXPR is a jq expression on the item, such
+// as now-.Time or .Score/.Descendants (see jq.go), or simply the name of
+// a field of the item, such as score, in any case.
+type field struct {
+	name string
+	expr jqFilter
+}
+
+// columns and lineFormat are the fields of -columns and the template of
+// -format, parsed by setup.
+var (
+	columns    []field
+	lineFormat []formatPart
+)
+
+// A formatPart is a piece of the template of -format: literal text, or a
+// field to print, if expr is not nil.
+type formatPart struct {
+	text string
+	expr jqFilter
+}
+
+// itemKeyNames maps the lower-case JSON keys of items to their case.
+var itemKeyNames = func() map[string]string {
+	data, _ := json.Marshal(item{Labels: []string{}})
+	var m map[string]any
+	json.Unmarshal(data, &m)
+	names := make(map[string]string)
+	for k := range m {
+		names[strings.ToLower(k)] = k
+	}
+	return names
+}()
+
+// parseField parses the field spec.
+func parseField(spec string) (field, error) {
+	name, expr, ok := strings.Cut(spec, "=")
+	// An = may also be part of the expression, as in .Score == 1.
+	if !ok || !isWord(strings.TrimSpace(name)) || strings.HasPrefix(expr, "=") {
+		name, expr = spec, spec
+	}
+	name, expr = strings.TrimSpace(name), strings.TrimSpace(expr)
+	if key, ok := itemKeyNames[strings.ToLower(expr)]; ok {
+		expr = "." + key
+	}
+	f, err := compileJQ(expr)
+	if err != nil {
+		return field{}, fmt.Errorf("%s: %v", spec, err)
+	}
+	return field{name, f}, nil
+}
+
+// parseColumns parses the comma-separated fields of -columns. Commas
+// within parentheses, brackets, braces or strings are part of a field.
+func parseColumns(s string) ([]field, error) {
+	var fields []field
+	for _, spec := range splitTopLevel(s, ',') {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		f, err := parseField(spec)
+		if err != nil {
+			return nil, fmt.Errorf("-columns: %v", err)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// splitTopLevel splits s at the separators sep that are not nested in
+// parentheses, brackets, braces or strings.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		case c == '"':
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseFormat parses the template of -format, text in which each {FIELD}
+// is replaced by the value of the field, and \t and \n by a tab and a
+// newline.
+func parseFormat(s string) ([]formatPart, error) {
+	var parts []formatPart
+	escapes := strings.NewReplacer(`\t`, "\t", `\n`, "\n")
+	for s != "" {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			parts = append(parts, formatPart{text: escapes.Replace(s)})
+			break
+		}
+		parts = append(parts, formatPart{text: escapes.Replace(s[:i])})
+		// The field ends at the brace that closes this one.
+		inner := splitTopLevel(s[i+1:], '}')
+		if len(inner) < 2 {
+			return nil, fmt.Errorf("-format: unclosed { in %q", s[i:])
+		}
+		f, err := parseField(inner[0])
+		if err != nil {
+			return nil, fmt.Errorf("-format: %v", err)
+		}
+		parts = append(parts, formatPart{expr: f.expr})
+		s = s[i+1+len(inner[0])+1:]
+	}
+	return parts, nil
+}
+
+// itemValue returns it as a JSON value, for fields to be computed on.
+func itemValue(it *item) (any, error) {
+	data, err := json.Marshal(it)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+// fieldText returns the values expr yields for v as text, separated by
+// commas: strings as they are, whole numbers without decimals, other
+// numbers with two, and the rest as JSON. Fields whose expression fails,
+// as .Score/.Descendants does for stories without comments, are empty.
+func fieldText(expr jqFilter, v any) string {
+	vs, err := expr(v)
+	if err != nil {
+		return ""
+	}
+	texts := make([]string, len(vs))
+	for i, v := range vs {
+		switch v := v.(type) {
+		case string:
+			texts[i] = v
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+				texts[i] = strconv.FormatInt(int64(v), 10)
+			} else {
+				texts[i] = strconv.FormatFloat(v, 'f', 2, 64)
+			}
+		case nil:
+		default:
+			texts[i] = jqJSON(v)
+		}
+	}
+	return strings.Join(texts, ",")
+}
+
+// printColumns writes r to w as a plain-text table of the fields of
+// -columns, headed by their names in upper case.
+func printColumns(w io.Writer, r *searchResult) error {
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c.name)
+	}
+	rows := [][]string{header}
+	for i := range r.Items {
+		v, err := itemValue(&r.Items[i])
+		if err != nil {
+			return err
+		}
+		row := make([]string, len(columns))
+		for j, c := range columns {
+			row[j] = fieldText(c.expr, v)
+		}
+		rows = append(rows, row)
+	}
+	widths := make([]int, len(columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	for _, row := range rows {
+		var b strings.Builder
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		b.WriteString("\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFormatted writes it to w as the template of -format says.
+func writeFormatted(w io.Writer, it *item) error {
+	v, err := itemValue(it)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, p := range lineFormat {
+		if p.expr == nil {
+			b.WriteString(p.text)
+		} else {
+			b.WriteString(fieldText(p.expr, v))
+		}
+	}
+	b.WriteString("\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,340 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getComments fetches the comments of the story, level by level, and
+// returns them in that order: the replies to the story first, then the
+// replies to those, and so on. Comments that cannot be fetched are noted
+// for -error-summary and left out, with their replies; so are those
+// hidden, as dead and deleted ones are unless -include-dead or
+// -include-deleted say otherwise.
+func getComments(ctx context.Context, story *item) []item {
+	comments, _ := fetchComments(ctx, story)
+	return comments
+}
+
+// fetchComments is like getComments, but also returns the IDs of the
+// comments that could not be fetched.
+func fetchComments(ctx context.Context, story *item) (comments []item, failed []int) {
+	level := story.Kids
+	for len(level) > 0 {
+		c := make(chan fetchResult, len(level))
+		for _, id := range level {
+			go fetch(ctx, id, c)
+		}
+		got := make(map[int]item, len(level))
+		for range level {
+			r := <-c
+			if r.err != nil {
+				noteError("comment", r.ID, r.err)
+				failed = append(failed, r.ID)
+				continue
+			}
+			if !r.item.hidden() {
+				got[r.ID] = r.item
+			}
+		}
+		// Keep the order of the site, which ranks the replies.
+		var next []int
+		for _, id := range level {
+			if it, ok := got[id]; ok {
+				comments = append(comments, it)
+				next = append(next, it.Kids...)
+			}
+		}
+		level = next
+	}
+	return comments, failed
+}
+
+// runWatchItem runs the watch-item command, which polls the story args[0]
+// every -interval and prints the comments posted since the last poll, or
+// only those wanted (see commentWanted) given the pattern args[1], if any,
+// as they appear. Comments that a poll could not fetch, and their replies,
+// are not taken for new when a later one does, since they may be old.
+func runWatchItem(args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		fatal(fmt.Errorf("watch-item: want a story ID and, optionally, a pattern"))
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		fatal(fmt.Errorf("watch-item: invalid story ID %q", args[0]))
+	}
+	var re *pattern
+	if len(args) > 1 {
+		if re, err = compile(args[1]); err != nil {
+			fatal(err)
+		}
+	}
+	seen := make(map[int]bool)
+	missed := make(map[int]bool) // comments that could not be fetched
+	for first := true; ; first = false {
+		story, err := getItem(id)
+		if err != nil {
+			// A failed poll is not worth stopping for; the next one
+			// will likely succeed.
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			comments, failed := fetchComments(context.Background(), story)
+			for _, id := range failed {
+				missed[id] = true
+			}
+			var fresh []item
+			for _, c := range comments {
+				wasMissed := missed[c.ID]
+				delete(missed, c.ID)
+				if seen[c.ID] {
+					continue
+				}
+				seen[c.ID] = true
+				if wasMissed {
+					// Its replies come after it, and may be old too.
+					for _, k := range c.Kids {
+						missed[k] = true
+					}
+					continue
+				}
+				fresh = append(fresh, c)
+			}
+			if first {
+				fmt.Printf("%s  %d  %s: %d comments\n", time.Now().In(location).Format("15:04"), id, story.PlainTitle(), len(fresh))
+			} else {
+				for i := range fresh {
+					if commentWanted(&fresh[i], re) {
+						printComment(os.Stdout, &fresh[i], 0)
+					}
+				}
+			}
+		}
+		if *interval <= 0 {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// printComment writes the comment it to w, as a line with its time,
+// author, ID and parent, followed by its text, converted from HTML, all
+// indented by depth levels.
+func printComment(w io.Writer, it *item, depth int) {
+	const indent = "    "
+	margin := strings.Repeat(indent, depth)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%s  %s  %d  (reply to %d)\n", margin, formatTime(it.Created()), it.By, it.ID, it.Parent)
+	width := maxTextWidth
+	if ow := outputWidth(w); ow > 0 {
+		width = min(width, ow)
+	}
+	width = max(width-len(margin)-len(indent), minTitleWidth)
+	for _, line := range strings.Split(htmlToText(it.Text, width), "\n") {
+		if line != "" {
+			line = margin + indent + line
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n")
+	io.WriteString(w, b.String())
+}
+
+// commentWanted reports whether the comment c is one to print: by one of
+// the authors of -by, if set, and matching re, if not nil.
+func commentWanted(c *item, re *pattern) bool {
+	if authors := splitList(*byAuthors); len(authors) > 0 &&
+		!slices.ContainsFunc(authors, func(a string) bool { return strings.EqualFold(a, c.By) }) {
+		return false
+	}
+	return re == nil || c.matches(re)
+}
+
+// runComments runs the comments command, which prints the comments of the
+// story args[0] as indented threads, or, with -flat, in the order they
+// were posted. Given a pattern, the last of args, or -by, it prints only
+// the comments wanted (see commentWanted), in threads with the comments
+// they reply to. Without a story ID, it does so for each story of the
+// list, as limited by -offset and -limit-ids, the front page by default,
+// that has comments wanted.
+func runComments(args []string) {
+	var ids []int
+	if len(args) > 0 {
+		if id, err := strconv.Atoi(args[0]); err == nil {
+			ids, args = []int{id}, args[1:]
+		}
+	}
+	if len(args) > 1 {
+		fatal(fmt.Errorf("comments: want a story ID, a pattern, or both"))
+	}
+	var re *pattern
+	if len(args) > 0 {
+		var err error
+		if re, err = compile(args[0]); err != nil {
+			fatal(err)
+		}
+	}
+	all := ids != nil // whether to print stories without comments wanted
+	if !all {
+		stories, err := getStories(listName())
+		if err != nil {
+			fatal(err)
+		}
+		limit := *limitIDs
+		if limit == 0 {
+			limit = frontPageSize
+		}
+		ids = sliceStories(stories, *offset, limit)
+	}
+	for _, id := range ids {
+		story, err := getItem(id)
+		if err != nil {
+			if all {
+				fatal(err)
+			}
+			noteError("item", id, err)
+			continue
+		}
+		printComments(os.Stdout, story, getComments(context.Background(), story), re, all)
+	}
+}
+
+// printComments writes the story and the comments of it that are wanted
+// to w, as runComments says. Unless all is set, stories without any are
+// left out.
+func printComments(w io.Writer, story *item, comments []item, re *pattern, all bool) {
+	byID := make(map[int]*item, len(comments))
+	for i := range comments {
+		byID[comments[i].ID] = &comments[i]
+	}
+	// Keep the comments wanted, and, for threads, what they reply to.
+	keep := make(map[int]bool)
+	wanted := 0
+	for i := range comments {
+		c := &comments[i]
+		if !commentWanted(c, re) {
+			continue
+		}
+		keep[c.ID] = true
+		wanted++
+		for p := byID[c.Parent]; p != nil && !*flatComments && !keep[p.ID]; p = byID[p.Parent] {
+			keep[p.ID] = true
+		}
+	}
+	if wanted == 0 && !all {
+		return
+	}
+	fmt.Fprintf(w, "%s\n%d points by %s, %d comments: %s\n\n", story.PlainTitle(), story.Score, story.By, len(comments), itemURL(story.ID))
+	if *flatComments {
+		flat := slices.Clone(comments)
+		slices.SortStableFunc(flat, func(a, b item) int { return cmp.Compare(a.Time, b.Time) })
+		for i := range flat {
+			if keep[flat[i].ID] {
+				printComment(w, &flat[i], 0)
+			}
+		}
+		return
+	}
+	replies := make(map[int][]*item)
+	for i := range comments {
+		c := &comments[i]
+		replies[c.Parent] = append(replies[c.Parent], c)
+	}
+	var printThread func(parent, depth int)
+	printThread = func(parent, depth int) {
+		for _, c := range replies[parent] {
+			if keep[c.ID] {
+				printComment(w, c, depth)
+				printThread(c.ID, depth+1)
+			}
+		}
+	}
+	printThread(story.ID, 0)
+}
+
+// A threadStats describes the shape of the discussion of a story.
+type threadStats struct {
+	Comments    int
+	MaxDepth    int     // of replies, those to the story being at 1
+	Commenters  int     // distinct authors
+	TopAuthors  []count // by comments
+	MostReplied []count // comments, by direct replies
+	Keywords    []count // by comments using them
+}
+
+// computeThreadStats computes the stats of the comments of story, as
+// getComments returns them.
+func computeThreadStats(story *item, comments []item) *threadStats {
+	st := &threadStats{Comments: len(comments)}
+	depth := map[int]int{story.ID: 0}
+	authors := make(map[string]int)
+	replies := make(map[string]int)
+	keywords := make(map[string]int)
+	for i := range comments {
+		c := &comments[i]
+		// Parents come first, in the order of getComments.
+		d := depth[c.Parent] + 1
+		depth[c.ID] = d
+		st.MaxDepth = max(st.MaxDepth, d)
+		if c.By != "" {
+			authors[c.By]++
+		}
+		if len(c.Kids) > 0 {
+			replies[fmt.Sprintf("%d by %s: %s", c.ID, c.By, truncate(strings.Join(strings.Fields(c.PlainText()), " "), 50))] = len(c.Kids)
+		}
+		seen := make(map[string]bool)
+		for _, w := range tokenize(c.PlainText()) {
+			if !stopWords[w] && !seen[w] {
+				keywords[w]++
+				seen[w] = true
+			}
+		}
+	}
+	st.Commenters = len(authors)
+	st.TopAuthors = mostCommon(authors, *topCount)
+	st.MostReplied = mostCommon(replies, *topCount)
+	st.Keywords = mostCommon(keywords, *topCount)
+	return st
+}
+
+func printThreadStats(w io.Writer, story *item, st *threadStats) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n\n", story.PlainTitle(), itemURL(story.ID))
+	fmt.Fprintf(&b, "comments    %d by %d users\n", st.Comments, st.Commenters)
+	fmt.Fprintf(&b, "max depth   %d\n", st.MaxDepth)
+	writeCounts(&b, "top commenters", st.TopAuthors)
+	writeCounts(&b, "most replied to", st.MostReplied)
+	writeCounts(&b, "keywords", st.Keywords)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// runThreadStats runs the thread-stats command, which prints the stats of
+// the comments of the story args[0], with rankings of -n entries.
+func runThreadStats(args []string) {
+	if len(args) != 1 {
+		fatal(fmt.Errorf("thread-stats: want a story ID"))
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		fatal(fmt.Errorf("thread-stats: invalid story ID %q", args[0]))
+	}
+	story, err := getItem(id)
+	if err != nil {
+		fatal(err)
+	}
+	st := computeThreadStats(story, getComments(context.Background(), story))
+	if err := printThreadStats(os.Stdout, story, st); err != nil {
+		fatal(err)
+	}
+}
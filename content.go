@@ -0,0 +1,184 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentClient fetches the pages stories link to.
+var contentClient = &http.Client{Timeout: 20 * time.Second}
+
+// matchContent fetches the pages the items link to and returns the items
+// whose page text matches the regular expression of re, with their
+// Content field set. At most
+// -content-workers pages are fetched at a time. Pages that cannot be
+// fetched are logged and skipped.
+func matchContent(items []item, re *pattern) []item {
+	sem := make(chan struct{}, max(*contentWorkers, 1))
+	var (
+		mu      sync.Mutex
+		matched []item
+		wg      sync.WaitGroup
+	)
+	for _, it := range items {
+		if it.URL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			text, err := fetchContent(it.URL, *contentMaxBytes)
+			if err != nil {
+				noteError("content", it.ID, err)
+				return
+			}
+			snippet, ok := matchSnippet(re.Regexp, text, *contextSize)
+			if !ok {
+				return
+			}
+			it.Content, it.Snippet = text, snippet
+			mu.Lock()
+			matched = append(matched, it)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return matched
+}
+
+// fetchContent returns the text of the HTML or plain-text page at rawURL,
+// reading at most limit bytes of it.
+func fetchContent(rawURL string, limit int64) (string, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html, text/plain;q=0.9")
+	resp, err := contentClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "text/plain", "":
+	default:
+		return "", fmt.Errorf("unsupported content type %q", mediaType)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return "", err
+	}
+	if mediaType == "text/plain" {
+		return string(body), nil
+	}
+	return articleText(string(body)), nil
+}
+
+var (
+	invisible = regexp.MustCompile(`(?is)<(script|style|noscript|template|svg|head)\b.*?</(script|style|noscript|template|svg|head)>|<!--.*?-->`)
+	blockTag  = regexp.MustCompile(`(?i)</?(p|div|br|li|h[1-6]|tr|section|article|blockquote|pre)\b[^>]*>`)
+	spaces    = regexp.MustCompile(`[ \t\r\f\v]+`)
+	newlines  = regexp.MustCompile(`\s*\n\s*`)
+)
+
+var (
+	boilerplate = regexp.MustCompile(`(?is)<(nav|header|footer|aside|form)\b.*?</(nav|header|footer|aside|form)>`)
+	container   = regexp.MustCompile(`(?is)<(article|main)\b[^>]*>(.*?)</(article|main)>`)
+	paragraph   = regexp.MustCompile(`(?is)<p\b[^>]*>(.*?)</p>`)
+)
+
+// minArticleLength is the length below which the paragraphs found in a page
+// are not taken to be its article.
+const minArticleLength = 200
+
+// articleText extracts the main text of an HTML page, in the manner of
+// Readability: navigation, headers, footers and asides are dropped, the
+// largest <article> or <main> element is preferred, and of that the
+// paragraphs are kept. Pages where no substantial text is found this way
+// are reduced to all their visible text.
+func articleText(page string) string {
+	page = invisible.ReplaceAllString(page, " ")
+	page = boilerplate.ReplaceAllString(page, " ")
+	body := ""
+	for _, m := range container.FindAllStringSubmatch(page, -1) {
+		if len(m[2]) > len(body) {
+			body = m[2]
+		}
+	}
+	if body == "" {
+		body = page
+	}
+	var paras []string
+	n := 0
+	for _, m := range paragraph.FindAllStringSubmatch(body, -1) {
+		if p := pageText(m[1]); p != "" {
+			paras = append(paras, strings.ReplaceAll(p, "\n", " "))
+			n += len(p)
+		}
+	}
+	if n < minArticleLength {
+		return pageText(page)
+	}
+	return strings.Join(paras, "\n")
+}
+
+// matchSnippet reports whether re matches text and returns the text around
+// the first match: up to n runes on each side, cut at word boundaries and
+// joined into a single line.
+func matchSnippet(re *regexp.Regexp, text string, n int) (string, bool) {
+	loc := re.FindStringIndex(text)
+	if loc == nil {
+		// The pattern may only match once the text is normalized.
+		text = normalize(text, *fold)
+		if loc = re.FindStringIndex(text); loc == nil {
+			return "", false
+		}
+	}
+	before := []rune(text[:loc[0]])
+	after := []rune(text[loc[1]:])
+	prefix, suffix := "", ""
+	if len(before) > n {
+		before = before[len(before)-n:]
+		if i := strings.IndexAny(string(before), " \n"); i >= 0 {
+			before = []rune(string(before)[i+1:])
+		}
+		prefix = "…"
+	}
+	if len(after) > n {
+		after = after[:n]
+		if i := strings.LastIndexAny(string(after), " \n"); i >= 0 {
+			after = []rune(string(after)[:i])
+		}
+		suffix = "…"
+	}
+	s := prefix + string(before) + text[loc[0]:loc[1]] + string(after) + suffix
+	return strings.Join(strings.Fields(s), " "), true
+}
+
+// pageText extracts the visible text of an HTML page, one line per block.
+func pageText(page string) string {
+	page = invisible.ReplaceAllString(page, " ")
+	page = blockTag.ReplaceAllString(page, "\n")
+	page = anyTag.ReplaceAllString(page, " ")
+	page = html.UnescapeString(page)
+	page = spaces.ReplaceAllString(page, " ")
+	page = newlines.ReplaceAllString(page, "\n")
+	return strings.TrimSpace(page)
+}
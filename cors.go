@@ -0,0 +1,50 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// allowCORS wraps h so that the web pages of the origins in -cors-origins
+// may call the serve command with the methods in -cors-methods. It
+// answers the preflight requests itself, since they carry no API key.
+// Only the origins listed by name may send credentials, such as cookies;
+// with *, any other is allowed without them.
+func allowCORS(h http.Handler) http.Handler {
+	origins := splitList(*corsOrigins)
+	if len(origins) == 0 {
+		return h
+	}
+	methods := strings.ToUpper(strings.Join(splitList(*corsMethods), ", "))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		switch {
+		case origin == "":
+			h.ServeHTTP(w, r)
+			return
+		case slices.Contains(origins, origin):
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		case slices.Contains(origins, "*"):
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		default:
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Expose-Headers", "Retry-After, X-Cache")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
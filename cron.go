@@ -0,0 +1,256 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A schedule tells when a saved search runs next.
+type schedule interface {
+	// next returns the first time the search runs after t.
+	next(t time.Time) time.Time
+}
+
+// parseSchedule returns the schedule of runs either a duration apart,
+// such as 30m, or at the times of a cron expression. It returns nil if
+// both are empty.
+func parseSchedule(interval, cron string) (schedule, error) {
+	switch {
+	case interval != "" && cron != "":
+		return nil, errors.New("both every and cron set")
+	case interval != "":
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid every %q", interval)
+		}
+		return every(d), nil
+	case cron != "":
+		return parseCron(cron)
+	}
+	return nil, nil
+}
+
+// every is a schedule of runs a fixed time apart.
+type every time.Duration
+
+func (d every) next(t time.Time) time.Time {
+	return t.Add(time.Duration(d))
+}
+
+// A cronSchedule is a schedule given by a cron expression: five fields
+// for the minute, hour, day of the month, month and day of the week, in
+// the time zone set with -tz. Each field is *, a number, a range such as
+// 1-5, or a list of those, optionally with a step, such as */15. Months
+// and days of the week can also be named by their first three letters.
+// As in cron, if both day fields are restricted, a day matches either.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bit sets of the values allowed
+	anyDay                        bool   // whether dom or dow is *
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = strings.Fields("jan feb mar apr may jun jul aug sep oct nov dec")
+	dayNames   = strings.Fields("sun mon tue wed thu fri sat")
+)
+
+// parseCron parses a cron expression.
+func parseCron(expr string) (*cronSchedule, error) {
+	if m, ok := cronMacros[expr]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: want 5 fields", expr)
+	}
+	c := new(cronSchedule)
+	var err error
+	parse := func(i int, set *uint64, lo, hi int, names []string, nameBase int) {
+		if err == nil {
+			*set, err = parseCronField(fields[i], lo, hi, names, nameBase)
+		}
+	}
+	parse(0, &c.minute, 0, 59, nil, 0)
+	parse(1, &c.hour, 0, 23, nil, 0)
+	parse(2, &c.dom, 1, 31, nil, 0)
+	parse(3, &c.month, 1, 12, monthNames, 1)
+	parse(4, &c.dow, 0, 7, dayNames, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cron %q: %v", expr, err)
+	}
+	if c.dow&(1<<7) != 0 { // 7 is also Sunday
+		c.dow |= 1
+	}
+	c.anyDay = fields[2] == "*" || fields[4] == "*"
+	return c, nil
+}
+
+// parseCronField parses a field of a cron expression with values between
+// lo and hi into a bit set. Names, if any, stand for nameBase onwards.
+func parseCronField(field string, lo, hi int, names []string, nameBase int) (uint64, error) {
+	value := func(s string) (int, error) {
+		for i, name := range names {
+			if strings.EqualFold(s, name) {
+				return nameBase + i, nil
+			}
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < lo || n > hi {
+			return 0, fmt.Errorf("invalid value %q: want %d to %d", s, lo, hi)
+		}
+		return n, nil
+	}
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+		first, last := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if first, err = value(a); err != nil {
+				return 0, err
+			}
+			last = first
+			if isRange {
+				if last, err = value(b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				last = hi
+			}
+			if first > last {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := first; v <= last; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (c *cronSchedule) next(t time.Time) time.Time {
+	t = t.In(location).Truncate(time.Minute).Add(time.Minute)
+	// Every combination of the fields recurs within some years, so give
+	// up only on expressions that never match, such as 0 0 30 2 *.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<t.Month()) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, location)
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, location)
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, location)
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *cronSchedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<t.Weekday()) != 0
+	if c.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// runScheduled runs the saved search s whenever sched says, and saves the
+// matches not in seen to the sinks of s, until ctx is done.
+func runScheduled(ctx context.Context, s savedSearch, sched schedule, seen map[int]bool) {
+	if s.List == "" {
+		s.List = "new"
+	}
+	for {
+		t := sched.next(time.Now())
+		if t.IsZero() {
+			log.Printf("%s: schedule never runs", s.Name)
+			return
+		}
+		if !sleepUntil(ctx, t) {
+			return
+		}
+		result, err := runSearch(s)
+		if err != nil {
+			log.Printf("%s: %v", s.Name, err)
+			continue
+		}
+		fresh := &searchResult{Items: unseen(seen, result.Items), re: result.re}
+		fresh.Total = len(fresh.Items)
+		log.Printf("%s: %d matches, %d new", s.Name, result.Total, fresh.Total)
+		if len(s.SaveTo) > 0 && fresh.Total > 0 {
+			if err := saveMatches(s.SaveTo, s.Name, fresh); err != nil {
+				log.Printf("%s: %v", s.Name, err)
+			}
+		}
+	}
+}
+
+// sleepUntil waits until t, and reports whether it got there before ctx
+// was done.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// seenSets holds the stories already saved by each job of the serve
+// command, so that they are not saved again when the jobs are restarted
+// by a reload. It is not safe for concurrent use: the sets are looked up
+// before the jobs start.
+type seenSets map[string]map[int]bool
+
+// get returns the set of the job called key, creating it if needed.
+func (s seenSets) get(key string) map[int]bool {
+	if s[key] == nil {
+		s[key] = make(map[int]bool)
+	}
+	return s[key]
+}
+
+// unseen returns the items whose IDs are not in seen, and adds them.
+func unseen(seen map[int]bool, items []item) []item {
+	var fresh []item
+	for _, it := range items {
+		if !seen[it.ID] {
+			seen[it.ID] = true
+			fresh = append(fresh, it)
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The sinks can be told not to get the same story again within a time
+// window, even if later runs match it again, with -dedup-window or, per
+// sink, the "dedup" section of the configuration file:
+//
+//	"dedup": {"mqtt": "24h", "pinboard": "720h"}
+//
+// When each story was last saved to each sink is kept in a state file,
+// shared by all runs.
+
+// A notifiedState maps sink names to the stories saved to them, and the
+// times they were saved, in Unix seconds.
+type notifiedState map[string]map[int]int64
+
+// stateMu serializes the use of the state files by concurrent
+// searches.
+var stateMu sync.Mutex
+
+// notifiedFile returns the path of the state file.
+func notifiedFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "news-notified.json"
+	}
+	return filepath.Join(dir, "news", "notified.json")
+}
+
+// dedupWindow returns the time window within which the sink name does not
+// get the same story twice. Zero disables the check.
+func dedupWindow(name string) time.Duration {
+	if s, ok := cfg().Dedup[name]; ok {
+		d, _ := time.ParseDuration(s) // checked when loading
+		return d
+	}
+	return *dedupAll
+}
+
+// loadNotified reads the state file. A missing file is an empty state.
+func loadNotified(file string) (notifiedState, error) {
+	state := make(notifiedState)
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return state, nil
+}
+
+// save writes the state to file, dropping the entries of the sinks names
+// older than their window, since they no longer hold back any story. The
+// entries of other sinks, and of those without a window, are kept: their
+// windows may have been set for other runs, with -dedup-window.
+func (s notifiedState) save(file string, names []string) error {
+	now := time.Now()
+	for _, name := range names {
+		saved, ok := s[name]
+		window := dedupWindow(name)
+		if !ok || window == 0 {
+			continue
+		}
+		for id, t := range saved {
+			if now.Sub(time.Unix(t, 0)) >= window {
+				delete(saved, id)
+			}
+		}
+		if len(saved) == 0 {
+			delete(s, name)
+		}
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a crash leaves the old
+	// state in place rather than a truncated one.
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// recent reports whether the story id was saved to the sink name within
+// its window.
+func (s notifiedState) recent(name string, id int, window time.Duration) bool {
+	t, ok := s[name][id]
+	return ok && time.Since(time.Unix(t, 0)) < window
+}
+
+// add records that the story id was saved to the sink name now.
+func (s notifiedState) add(name string, id int) {
+	if s[name] == nil {
+		s[name] = make(map[int]int64)
+	}
+	s[name][id] = time.Now().Unix()
+}
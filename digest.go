@@ -0,0 +1,206 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Sinks that deliver messages, such as email, kafka, mqtt and nats, can send
+// digests: rather than a message per match, the matches are queued and
+// sent together, in a single message, every so often. The interval is
+// set per sink in the "digest" section of the configuration file:
+//
+//	"digest": {"email": "24h"}
+//
+// The queue is kept in a state file, so that matches queued by separate
+// runs go in the same digest. A run sends the digest when it is due,
+// and so does the serve command, without waiting for new matches.
+
+// A digest holds the stories queued for a sink.
+type digest struct {
+	Since int64    `json:"since"` // Unix time of the last delivery, or of the first queued story
+	Tags  []string `json:"tags"`
+	Items []item   `json:"items"`
+}
+
+// digestFile returns the path of the state file of the digests.
+func digestFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "news-digest.json"
+	}
+	return filepath.Join(dir, "news", "digest.json")
+}
+
+// digestInterval returns the interval at which the sink name sends
+// digests, or zero if it saves each story as it comes.
+func digestInterval(name string) time.Duration {
+	d, _ := time.ParseDuration(cfg().Digest[name]) // checked when loading
+	return d
+}
+
+func loadDigests(file string) (map[string]*digest, error) {
+	digests := make(map[string]*digest)
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return digests, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &digests); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return digests, nil
+}
+
+func saveDigests(file string, digests map[string]*digest) error {
+	data, err := json.Marshal(digests)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// queueDigest adds items to the digest of the sink name, s, and sends
+// the digest if it is due.
+func queueDigest(name string, s sink, tags []string, items []item) error {
+	bs, ok := s.(batchSink)
+	if !ok {
+		return errors.New("digests are not supported by this sink")
+	}
+	d, err := takeDigest(name, tags, items)
+	if err != nil || d == nil {
+		return err
+	}
+	return sendDigest(name, bs, d)
+}
+
+// takeDigest adds items and tags to the digest of the sink name and, if
+// its interval has passed since the last delivery, empties it and returns
+// what it held, to be sent with sendDigest without holding stateMu.
+func takeDigest(name string, tags []string, items []item) (*digest, error) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	digests, err := loadDigests(digestFile())
+	if err != nil {
+		return nil, err
+	}
+	d := digests[name]
+	if d == nil {
+		d = &digest{Since: time.Now().Unix()}
+		digests[name] = d
+	}
+	addToDigest(d, tags, items)
+	var due *digest
+	if time.Since(time.Unix(d.Since, 0)) >= digestInterval(name) {
+		taken := *d
+		due = &taken
+		*d = digest{Since: time.Now().Unix()}
+	}
+	if err := saveDigests(digestFile(), digests); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// addToDigest adds to d the items and tags it does not have yet.
+func addToDigest(d *digest, tags []string, items []item) {
+	for _, it := range items {
+		if !slices.ContainsFunc(d.Items, func(q item) bool { return q.ID == it.ID }) {
+			d.Items = append(d.Items, it)
+		}
+	}
+	for _, t := range tags {
+		if !slices.Contains(d.Tags, t) {
+			d.Tags = append(d.Tags, t)
+		}
+	}
+}
+
+// sendDigest sends d, taken from the digest of the sink name, s, by
+// takeDigest. If that fails, d is put back, to be sent when the digest is
+// next due, which it is already.
+func sendDigest(name string, s batchSink, d *digest) error {
+	if len(d.Items) == 0 {
+		return nil
+	}
+	err := s.saveBatch(d.Items, d.Tags)
+	if err == nil {
+		log.Printf("%s: sent digest of %d stories", name, len(d.Items))
+		return nil
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	digests, lerr := loadDigests(digestFile())
+	if lerr != nil {
+		return errors.Join(err, lerr)
+	}
+	// The stories taken go first, as they were queued first, and d keeps
+	// the time of the last delivery, before that of the digest now.
+	if cur := digests[name]; cur != nil {
+		addToDigest(d, cur.Tags, cur.Items)
+	}
+	digests[name] = d
+	return errors.Join(err, saveDigests(digestFile(), digests))
+}
+
+// deliverPending sends the queued digests that are due and retries the
+// stories queued after failing to save, for the serve command to call
+// periodically.
+func deliverPending() {
+	if err := retryQueued(); err != nil {
+		log.Printf("queue: %v", err)
+	}
+	stateMu.Lock()
+	digests, err := loadDigests(digestFile())
+	stateMu.Unlock()
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	for name, d := range digests {
+		if len(d.Items) == 0 || time.Since(time.Unix(d.Since, 0)) < digestInterval(name) {
+			continue
+		}
+		newSink, ok := sinks[name]
+		if !ok {
+			continue
+		}
+		s, err := newSink(cfg())
+		if err != nil {
+			log.Printf("%s: %v", name, err)
+			continue
+		}
+		bs, ok := s.(batchSink)
+		if !ok {
+			continue
+		}
+		// Take the digest again, as another search may have sent it
+		// since it was loaded.
+		taken, err := takeDigest(name, nil, nil)
+		if err == nil && taken != nil {
+			err = sendDigest(name, bs, taken)
+		}
+		if err != nil {
+			log.Printf("%s: sending digest: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsCacheTTL is how long a host name lookup is reused. A run makes
+// hundreds of requests to the same few hosts, and some resolvers are
+// slow; the limit only matters to the serve command, which runs for long.
+const dnsCacheTTL = 5 * time.Minute
+
+// A dnsCache dials connections to host names resolved at most once per
+// dnsCacheTTL.
+type dnsCache struct {
+	dialer *net.Dialer
+	family string // "ip", or "ip4" or "ip6" to use only that address family
+
+	mu    sync.Mutex
+	hosts map[string]*dnsEntry
+}
+
+type dnsEntry struct {
+	done    chan struct{} // closed when the lookup is over
+	addrs   []net.IP
+	err     error
+	expires time.Time
+}
+
+func newDNSCache(family string) *dnsCache {
+	return &dnsCache{
+		dialer: &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		family: family,
+		hosts:  make(map[string]*dnsEntry),
+	}
+}
+
+// lookup returns the addresses of host.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	c.mu.Lock()
+	e, ok := c.hosts[host]
+	if ok {
+		select {
+		case <-e.done:
+			if e.err != nil || time.Now().After(e.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		e = &dnsEntry{done: make(chan struct{})}
+		c.hosts[host] = e
+		c.mu.Unlock()
+		// The lookup is shared, so it must not be cut short by the
+		// context of the first caller.
+		e.addrs, e.err = net.DefaultResolver.LookupIP(context.WithoutCancel(ctx), c.family, host)
+		e.expires = time.Now().Add(dnsCacheTTL)
+		close(e.done)
+		return e.addrs, e.err
+	}
+	c.mu.Unlock()
+	select {
+	case <-e.done:
+		return e.addrs, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// As net.Dialer does, dialContext tries the addresses of a host of each
+// family in turn, giving each an equal share of the time left, but no
+// less than minDialTimeout; and if the host has addresses of both
+// families, it races those of the second against those of the first,
+// starting fallbackDelay after them.
+const (
+	minDialTimeout = 2 * time.Second
+	fallbackDelay  = 300 * time.Millisecond
+)
+
+// dialContext dials addr on network, resolving its host with lookup.
+func (c *dnsCache) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	switch c.family {
+	case "ip4":
+		network = "tcp4"
+	case "ip6":
+		network = "tcp6"
+	}
+	if net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, addr)
+	}
+	ips, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	deadline := time.Now().Add(c.dialer.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	var primaries, fallbacks []net.IP
+	for _, ip := range ips {
+		if (ip.To4() != nil) == (ips[0].To4() != nil) {
+			primaries = append(primaries, ip)
+		} else {
+			fallbacks = append(fallbacks, ip)
+		}
+	}
+	if len(fallbacks) == 0 {
+		return c.dialSerial(ctx, network, primaries, port)
+	}
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, 2)
+	race := func(ips []net.IP) {
+		conn, err := c.dialSerial(ctx, network, ips, port)
+		results <- result{conn, err}
+	}
+	go race(primaries)
+	pending := 1
+	timer := time.NewTimer(fallbackDelay)
+	defer timer.Stop()
+	fallback := timer.C
+	var errs []error
+	for {
+		select {
+		case <-fallback:
+			fallback = nil
+			pending++
+			go race(fallbacks)
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// The other, cancelled when this returns, may connect
+				// all the same.
+				go func(n int) {
+					for range n {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+			errs = append(errs, r.err)
+			if fallback != nil {
+				// The primaries failed before the fallbacks were due.
+				fallback = nil
+				pending++
+				go race(fallbacks)
+			} else if pending == 0 {
+				return nil, errors.Join(errs...)
+			}
+		}
+	}
+}
+
+// dialSerial dials the addresses ips at port in turn, until one connects
+// or the deadline of ctx passes.
+func (c *dnsCache) dialSerial(ctx context.Context, network string, ips []net.IP, port string) (net.Conn, error) {
+	deadline, _ := ctx.Deadline()
+	var errs []error
+	for i, ip := range ips {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		left := time.Until(deadline)
+		timeout := max(left/time.Duration(len(ips)-i), min(minDialTimeout, left))
+		dctx, cancel := context.WithTimeout(ctx, timeout)
+		conn, err := c.dialer.DialContext(dctx, network, net.JoinHostPort(ip.String(), port))
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
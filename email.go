@@ -0,0 +1,299 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// emailTimeout bounds the time to connect to the server and send a mail.
+const emailTimeout = 30 * time.Second
+
+// email mails stories over SMTP, best as digests: each mail has an HTML
+// part, made for mail clients, with the styles inline and no scripts, and
+// a plain-text alternative. The subject and both parts are templates,
+// which can be replaced with those in the files set in the configuration
+// file; they are executed with an emailData.
+type email struct {
+	server   *url.URL
+	from     string
+	to       []string
+	subject  *template.Template
+	html     *htmltemplate.Template
+	text     *template.Template
+	username string
+	password string
+}
+
+// emailData is the data the templates of the email sink are executed
+// with.
+type emailData struct {
+	Search string   // the tag of the search, or "hn" if it has no name
+	Tags   []string // the tags of the stories
+	Items  []item
+	Date   time.Time
+}
+
+func newEmail(c *config) (sink, error) {
+	server := c.Email.SMTP
+	if server == "" {
+		server = os.Getenv("SMTP_URL")
+	}
+	if server == "" {
+		return nil, errors.New("no server: set email.smtp in the config file or $SMTP_URL")
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "smtp", "smtps":
+	default:
+		return nil, fmt.Errorf("invalid server %q: want smtp://HOST:PORT or smtps://HOST:PORT", server)
+	}
+	if len(c.Email.To) == 0 {
+		return nil, errors.New("no recipients: set email.to in the config file")
+	}
+	e := &email{server: u, from: c.Email.From, to: c.Email.To}
+	if e.from == "" {
+		e.from = "news@" + serverHost(u)
+	}
+	for _, addr := range append([]string{e.from}, e.to...) {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %v", addr, err)
+		}
+	}
+	if u.User != nil {
+		e.username = u.User.Username()
+		e.password, _ = u.User.Password()
+	}
+	subject := c.Email.Subject
+	if subject == "" {
+		subject = defaultEmailSubject
+	}
+	if e.subject, err = template.New("subject").Funcs(emailFuncs).Parse(subject); err != nil {
+		return nil, fmt.Errorf("subject: %v", err)
+	}
+	e.html = emailHTMLTemplate
+	if f := c.Email.HTMLTemplate; f != "" {
+		if e.html, err = htmltemplate.New(filepath.Base(f)).Funcs(emailFuncs).ParseFiles(f); err != nil {
+			return nil, err
+		}
+	}
+	e.text = emailTextTemplate
+	if f := c.Email.TextTemplate; f != "" {
+		if e.text, err = template.New(filepath.Base(f)).Funcs(emailFuncs).ParseFiles(f); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// serverHost returns the host name of u, for the default sender and the
+// message IDs.
+func serverHost(u *url.URL) string {
+	if h := u.Hostname(); h != "" {
+		return h
+	}
+	return "localhost"
+}
+
+func (e *email) save(it *item, tags []string) error {
+	return e.saveBatch([]item{*it}, tags)
+}
+
+func (e *email) saveBatch(items []item, tags []string) error {
+	data := emailData{Search: "hn", Tags: tags, Items: items, Date: time.Now()}
+	if len(tags) > 1 {
+		data.Search = tags[1]
+	}
+	msg, err := e.message(&data)
+	if err != nil {
+		return err
+	}
+	return e.send(msg)
+}
+
+// message returns the mail with the stories in data.
+func (e *email) message(data *emailData) ([]byte, error) {
+	var subject strings.Builder
+	if err := e.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("subject: %v", err)
+	}
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "From: %s\r\n", e.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&buf, "Date: %s\r\n", data.Date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", rand.Text(), serverHost(e.server))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	// The plain text goes first: clients show the last part they can.
+	parts := []struct {
+		contentType string
+		execute     func(io.Writer) error
+	}{
+		{"text/plain", func(w io.Writer) error { return e.text.Execute(w, data) }},
+		{"text/html", func(w io.Writer) error { return e.html.Execute(w, data) }},
+	}
+	for _, p := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if err := p.execute(qp); err != nil {
+			return nil, fmt.Errorf("%s template: %v", p.contentType, err)
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// send sends msg through the server, over TLS from the start with smtps,
+// or upgrading the connection with STARTTLS where the server offers it.
+func (e *email) send(msg []byte) error {
+	host := hostPort(e.server, "587")
+	if e.server.Scheme == "smtps" {
+		host = hostPort(e.server, "465")
+	}
+	tc := tlsConfig.Clone()
+	tc.ServerName = e.server.Hostname()
+	dialer := &net.Dialer{Timeout: emailTimeout}
+	var conn net.Conn
+	var err error
+	if e.server.Scheme == "smtps" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, tc)
+	} else {
+		conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(emailTimeout))
+	c, err := smtp.NewClient(conn, e.server.Hostname())
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok && e.server.Scheme == "smtp" {
+		if err := c.StartTLS(tc); err != nil {
+			return err
+		}
+	}
+	if e.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.username, e.password, e.server.Hostname())); err != nil {
+			return err
+		}
+	}
+	from, _ := mail.ParseAddress(e.from)
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		addr, _ := mail.ParseAddress(to)
+		if err := c.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+var emailFuncs = map[string]any{
+	"hostname":   hostname,
+	"itemURL":    itemURL,
+	"storyURL":   func(it item) string { return storyURL(&it) },
+	"userURL":    func(name string) string { return hnURL + "/user?id=" + name },
+	"formatDate": func(t time.Time) string { return t.In(location).Format("Mon, 2 Jan 2006 15:04 MST") },
+}
+
+const defaultEmailSubject = `{{len .Items}} Hacker News {{if eq (len .Items) 1}}story{{else}}stories{{end}} for {{.Search}}`
+
+var emailTextTemplate = template.Must(template.New("text").Funcs(emailFuncs).Parse(
+	`Hacker News stories for {{.Search}}, {{formatDate .Date}}
+{{range .Items}}
+{{.PlainTitle}}
+{{storyURL .}}
+{{.Score}} points by {{.By}}, {{.Descendants}} comments: {{itemURL .ID}}
+{{end}}`))
+
+// emailHTMLTemplate lays out the stories with a table and inline styles,
+// which are what mail clients render most consistently.
+var emailHTMLTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(emailFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Hacker News stories for {{.Search}}</title>
+</head>
+<body style="margin: 0; padding: 0; background: #f6f6ef;">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0" style="background: #f6f6ef;">
+<tr>
+<td align="center" style="padding: 16px;">
+<table role="presentation" width="600" cellpadding="0" cellspacing="0" border="0" style="max-width: 600px; width: 100%; background: #ffffff; font-family: Verdana, Geneva, sans-serif; font-size: 14px; line-height: 1.4; color: #000000;">
+<tr>
+<td style="background: #ff6600; padding: 8px 12px; font-weight: bold;">Hacker News stories for {{.Search}}</td>
+</tr>
+{{- range .Items}}
+<tr>
+<td style="padding: 10px 12px; border-bottom: 1px solid #eeeee4;">
+<a href="{{storyURL .}}" style="color: #000000; text-decoration: none; font-size: 15px;">{{.PlainTitle}}</a>
+{{- if .URL}} <span style="color: #828282; font-size: 12px;">({{hostname .URL}})</span>{{end}}
+<div style="color: #828282; font-size: 12px; padding-top: 2px;">
+{{.Score}} points by <a href="{{userURL .By}}" style="color: #828282;">{{.By}}</a> |
+<a href="{{itemURL .ID}}" style="color: #828282;">{{.Descendants}} comments</a>
+</div>
+</td>
+</tr>
+{{- end}}
+<tr>
+<td style="padding: 8px 12px; color: #828282; font-size: 11px;">Sent by news on {{formatDate .Date}}.</td>
+</tr>
+</table>
+</td>
+</tr>
+</table>
+</body>
+</html>
+`))
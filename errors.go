@@ -0,0 +1,185 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxErrorIDs bounds the IDs of the items kept for each kind of error.
+const maxErrorIDs = 100
+
+// runErrors collects the errors that did not stop the run, such as items
+// that could not be fetched, for the summary printed at the end of it.
+var runErrors struct {
+	mu      sync.Mutex
+	kinds   map[errorKind]*errorCount
+	fetches int // of stories, or their titles, for -max-error-rate
+}
+
+// An errorKind is what failed, such as fetch or content, and how, such as
+// timeout or network.
+type errorKind struct {
+	Stage string `json:"stage"`
+	Type  string `json:"type"`
+}
+
+type errorCount struct {
+	errorKind
+	Count   int    `json:"count"`
+	IDs     []int  `json:"ids"`     // of the items affected, up to maxErrorIDs
+	Example string `json:"example"` // the first error
+}
+
+// noteError logs err, the failure of stage for the item id, and counts it
+// for the summary.
+func noteError(stage string, id int, err error) {
+	log.Printf("%s %d: %v", stage, id, err)
+	k := errorKind{stage, errorType(err)}
+	runErrors.mu.Lock()
+	defer runErrors.mu.Unlock()
+	if runErrors.kinds == nil {
+		runErrors.kinds = make(map[errorKind]*errorCount)
+	}
+	c := runErrors.kinds[k]
+	if c == nil {
+		c = &errorCount{errorKind: k, Example: err.Error()}
+		runErrors.kinds[k] = c
+	}
+	c.Count++
+	if len(c.IDs) < maxErrorIDs {
+		c.IDs = append(c.IDs, id)
+	}
+}
+
+// noteFetches counts n stories, or titles of stories, about to be fetched.
+func noteFetches(n int) {
+	runErrors.mu.Lock()
+	runErrors.fetches += n
+	runErrors.mu.Unlock()
+}
+
+// fetchErrorRate returns the number of stories, or titles, that could not
+// be fetched, and the fraction of those fetched they are.
+func fetchErrorRate() (failed int, rate float64) {
+	runErrors.mu.Lock()
+	defer runErrors.mu.Unlock()
+	for k, c := range runErrors.kinds {
+		if k.Stage == "item" || k.Stage == "title" {
+			failed += c.Count
+		}
+	}
+	if runErrors.fetches == 0 {
+		return 0, 0
+	}
+	return failed, float64(failed) / float64(runErrors.fetches)
+}
+
+// maxErrorRate is the fraction of -max-error-rate, parsed by setup, or a
+// negative number if it is not set.
+var maxErrorRate = -1.0
+
+// parseErrorRate parses a rate given as a percentage, such as 5%, or as a
+// fraction, such as 0.05.
+func parseErrorRate(s string) (float64, error) {
+	pct := strings.HasSuffix(s, "%")
+	r, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if pct {
+		r /= 100
+	}
+	if err != nil || r < 0 || r > 1 {
+		return 0, fmt.Errorf("invalid rate %q: want a percentage, such as 5%%, or a fraction, such as 0.05", s)
+	}
+	return r, nil
+}
+
+// checkErrorRate exits with status 2, after the reports of the run, if
+// more stories could not be fetched than -max-error-rate allows.
+func checkErrorRate() {
+	if maxErrorRate < 0 {
+		return
+	}
+	failed, rate := fetchErrorRate()
+	if rate > maxErrorRate {
+		reportErrors()
+		reportTimings()
+		fatal(fmt.Errorf("%d stories (%.1f%%) could not be fetched, more than -max-error-rate %s", failed, rate*100, *errorRateSpec))
+	}
+}
+
+// errorType classifies err: timeout, network, decode or other.
+func errorType(err error) string {
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case netErr != nil:
+		return "network"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return "decode"
+	}
+	return "other"
+}
+
+// errorCounts returns the errors collected, the most frequent first.
+func errorCounts() []errorCount {
+	runErrors.mu.Lock()
+	defer runErrors.mu.Unlock()
+	var counts []errorCount
+	for _, c := range runErrors.kinds {
+		counts = append(counts, *c)
+	}
+	slices.SortFunc(counts, func(a, b errorCount) int {
+		return cmp.Or(b.Count-a.Count, cmp.Compare(a.Stage, b.Stage), cmp.Compare(a.Type, b.Type))
+	})
+	return counts
+}
+
+// reportErrors prints a summary of the errors collected during the run to
+// standard error, as -error-summary says, if there were any.
+func reportErrors() {
+	counts := errorCounts()
+	if len(counts) == 0 || *errorSummary == "none" {
+		return
+	}
+	total := 0
+	for _, c := range counts {
+		total += c.Count
+	}
+	if *errorSummary == "json" {
+		data, err := json.Marshal(map[string]any{"total": total, "errors": counts})
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", data)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "errors:     %d\n", total)
+	for _, c := range counts {
+		ids := make([]string, len(c.IDs))
+		for i, id := range c.IDs {
+			ids[i] = fmt.Sprint(id)
+		}
+		more := ""
+		if c.Count > len(c.IDs) {
+			more = ", ..."
+		}
+		fmt.Fprintf(os.Stderr, "  %s/%s: %d (%s%s)\n", c.Stage, c.Type, c.Count, strings.Join(ids, ", "), more)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// export writes the items of r to dest, which has the form KIND:FILE or
+// just FILE, in which case the kind is told by the file extension.
+// list is the story list that was searched.
+func export(dest string, list string, r *searchResult) error {
+	kind, file, ok := strings.Cut(dest, ":")
+	if !ok {
+		kind, file = exportKinds[filepath.Ext(dest)], dest
+	}
+	if kind == "" || file == "" {
+		return fmt.Errorf("invalid -export %q: want KIND:FILE", dest)
+	}
+	switch kind {
+	case "sqlite":
+		return exportSQLite(file, list, r)
+	case "parquet":
+		return exportParquet(file, r)
+	case "bookmarks":
+		return exportBookmarks(file, r)
+	}
+	return fmt.Errorf("invalid -export %q: unknown kind %q", dest, kind)
+}
+
+// exportKinds maps file extensions to the export kind they imply.
+var exportKinds = map[string]string{
+	".db":      "sqlite",
+	".sqlite":  "sqlite",
+	".sqlite3": "sqlite",
+	".parquet": "parquet",
+	".html":    "bookmarks",
+	".htm":     "bookmarks",
+}
+
+// sqliteSchema keeps one row per item, updated with its latest state, and
+// one row per run, linked to the items it matched along with the points
+// and comments they had at the time, and the patterns they matched when
+// searching for several.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS items (
+	id INTEGER PRIMARY KEY,
+	type TEXT NOT NULL,
+	by TEXT NOT NULL,
+	time INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	url TEXT NOT NULL,
+	text TEXT NOT NULL,
+	score INTEGER NOT NULL,
+	descendants INTEGER NOT NULL,
+	dead INTEGER NOT NULL,
+	deleted INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY,
+	time INTEGER NOT NULL,
+	list TEXT NOT NULL,
+	pattern TEXT NOT NULL,
+	matches INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS run_items (
+	run_id INTEGER NOT NULL REFERENCES runs (id),
+	item_id INTEGER NOT NULL REFERENCES items (id),
+	score INTEGER NOT NULL,
+	descendants INTEGER NOT NULL,
+	PRIMARY KEY (run_id, item_id)
+);
+CREATE TABLE IF NOT EXISTS run_item_labels (
+	run_id INTEGER NOT NULL,
+	item_id INTEGER NOT NULL,
+	label TEXT NOT NULL,
+	FOREIGN KEY (run_id, item_id) REFERENCES run_items
+);
+`
+
+// exportSQLite adds the items of r to the SQLite database file, creating
+// it if needed, and records the run. The work is done by the sqlite3
+// command, which must be installed, so that no database driver needs to
+// be linked in.
+func exportSQLite(file string, list string, r *searchResult) error {
+	var b strings.Builder
+	b.WriteString("PRAGMA foreign_keys = ON;\nBEGIN;\n")
+	b.WriteString(sqliteSchema)
+	pattern := ""
+	if r.re != nil {
+		pattern = r.re.String()
+	}
+	fmt.Fprintf(&b, "INSERT INTO runs (time, list, pattern, matches) VALUES (%d, %s, %s, %d);\n",
+		time.Now().Unix(), sqlQuote(list), sqlQuote(pattern), r.Total)
+	for i := range r.Items {
+		it := &r.Items[i]
+		fmt.Fprintf(&b, `INSERT INTO items VALUES (%d, %s, %s, %d, %s, %s, %s, %d, %d, %d, %d)
+	ON CONFLICT (id) DO UPDATE SET title = excluded.title, url = excluded.url, text = excluded.text,
+	score = excluded.score, descendants = excluded.descendants, dead = excluded.dead, deleted = excluded.deleted;
+`,
+			it.ID, sqlQuote(it.Type), sqlQuote(it.By), it.Time, sqlQuote(it.PlainTitle()),
+			sqlQuote(it.URL), sqlQuote(it.Text), it.Score, it.Descendants, sqlBool(it.Dead), sqlBool(it.Deleted))
+		fmt.Fprintf(&b, "INSERT INTO run_items VALUES ((SELECT max(id) FROM runs), %d, %d, %d);\n",
+			it.ID, it.Score, it.Descendants)
+		for _, l := range it.Labels {
+			fmt.Fprintf(&b, "INSERT INTO run_item_labels VALUES ((SELECT max(id) FROM runs), %d, %s);\n",
+				it.ID, sqlQuote(l))
+		}
+	}
+	b.WriteString("COMMIT;\n")
+
+	cmd := exec.Command("sqlite3", "-bail", file)
+	cmd.Stdin = strings.NewReader(b.String())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("sqlite3: %s", msg)
+		}
+		return fmt.Errorf("sqlite3: %v", err)
+	}
+	return nil
+}
+
+// exportBookmarks writes the items of r to file in the Netscape bookmark
+// file format, which browsers can import. The bookmarks are put in a
+// folder named after the pattern.
+func exportBookmarks(file string, r *searchResult) error {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`)
+	folder := "Hacker News"
+	if r.re != nil {
+		folder += ": " + r.re.String()
+	}
+	now := time.Now().Unix()
+	fmt.Fprintf(&b, "    <DT><H3 ADD_DATE=\"%d\" LAST_MODIFIED=\"%d\">%s</H3>\n    <DL><p>\n",
+		now, now, html.EscapeString(folder))
+	for i := range r.Items {
+		it := &r.Items[i]
+		link := it.URL
+		if link == "" {
+			link = itemURL(it.ID)
+		}
+		tags := ""
+		if len(it.Labels) > 0 {
+			tags = fmt.Sprintf(" TAGS=\"%s\"", html.EscapeString(strings.Join(it.Labels, ",")))
+		}
+		fmt.Fprintf(&b, "        <DT><A HREF=\"%s\" ADD_DATE=\"%d\"%s>%s</A>\n",
+			html.EscapeString(link), it.Time, tags, html.EscapeString(it.PlainTitle()))
+		fmt.Fprintf(&b, "        <DD>%d points, %d comments: %s", it.Score, it.Descendants, itemURL(it.ID))
+		if it.Archive != "" {
+			fmt.Fprintf(&b, " (archived at %s)", html.EscapeString(it.Archive))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("    </DL><p>\n</DL><p>\n")
+	return os.WriteFile(file, []byte(b.String()), 0o644)
+}
+
+// sqlQuote returns s as an SQL string literal.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlBool returns b as an SQLite boolean.
+func sqlBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+)
+
+// frontPageSize is the number of stories on the HN front page.
+const frontPageSize = 30
+
+// A frontPage is a snapshot of the top stories list, used to tell where
+// stories rank on the HN front page.
+type frontPage struct {
+	ranks   map[int]int // position of each top story, from 1
+	weights []float64   // ranking weight of the front page stories, in order
+}
+
+// getFrontPage fetches the top stories list. If estimate is set, it also
+// fetches the front page stories, so that stories outside the list can be
+// placed among them.
+func getFrontPage(estimate bool) (*frontPage, error) {
+	ids, err := getStories("top")
+	if err != nil {
+		return nil, err
+	}
+	fp := &frontPage{ranks: make(map[int]int, len(ids))}
+	for i, id := range ids {
+		fp.ranks[id] = i + 1
+	}
+	if !estimate {
+		return fp, nil
+	}
+	ids = ids[:min(len(ids), frontPageSize)]
+	c := make(chan fetchResult, len(ids))
+	for _, id := range ids {
+		go fetch(context.Background(), id, c)
+	}
+	now := time.Now()
+	for range ids {
+		r := <-c
+		if r.err != nil {
+			return nil, r.err
+		}
+		fp.weights = append(fp.weights, rankWeight(&r.item, now))
+	}
+	return fp, nil
+}
+
+// rank returns the position of the story it in the top stories list.
+// Stories missing from the list are placed by their ranking weight among
+// the front page stories, if those were fetched, and estimated is set.
+// A rank of 0 means the story is not on the front page.
+func (fp *frontPage) rank(it *item) (rank int, estimated bool) {
+	if r, ok := fp.ranks[it.ID]; ok {
+		return r, false
+	}
+	if len(fp.weights) == 0 {
+		return 0, false
+	}
+	w := rankWeight(it, time.Now())
+	above := 0
+	for _, fw := range fp.weights {
+		if fw > w {
+			above++
+		}
+	}
+	if above >= frontPageSize {
+		return 0, false
+	}
+	return above + 1, true
+}
+
+// rankWeight returns the weight HN is known to rank stories by:
+// (points-1) / (age in hours + 2)^1.8. Penalties and flags are not
+// public, so the result is only an approximation.
+func rankWeight(it *item, now time.Time) float64 {
+	hours := now.Sub(it.Created()).Hours()
+	return float64(it.Score-1) / math.Pow(max(hours, 0)+2, 1.8)
+}
+
+// formatRank formats a rank returned by frontPage.rank for display.
+func formatRank(rank int, estimated bool) string {
+	switch {
+	case rank == 0:
+		return "-"
+	case estimated:
+		return "~" + strconv.Itoa(rank)
+	}
+	return strconv.Itoa(rank)
+}
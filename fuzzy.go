@@ -0,0 +1,63 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A fuzzyTerm matches the text that contains the term, ignoring case,
+// within maxEdits insertions, deletions or substitutions of characters,
+// so that "kubernetes" with 2 edits matches "Kuberentes 1.31 released".
+type fuzzyTerm struct {
+	term     []rune // in lower case
+	maxEdits int
+}
+
+func newFuzzyTerm(term string, maxEdits int) (*fuzzyTerm, error) {
+	if n := utf8.RuneCountInString(term); maxEdits >= n {
+		return nil, fmt.Errorf("-fuzzy %d would match anything: %q has only %d characters", maxEdits, term, n)
+	}
+	return &fuzzyTerm{[]rune(strings.ToLower(term)), maxEdits}, nil
+}
+
+// match reports whether s contains t within t.maxEdits edits.
+//
+// It computes the edit distance between the term and the best matching
+// substring of s, a column of the dynamic programming table per
+// character of s, in which a match may start anywhere (Sellers, 1980).
+func (t *fuzzyTerm) match(s string) bool {
+	// col[i] is the least number of edits to turn the first i characters
+	// of the term into a substring of s ending at the current character.
+	col := make([]int, len(t.term)+1)
+	for i := range col {
+		col[i] = i
+	}
+	if col[len(t.term)] <= t.maxEdits {
+		return true
+	}
+	for _, r := range strings.ToLower(s) {
+		diag := col[0] // the previous column's value above the current cell
+		for i, c := range t.term {
+			cost := 1
+			if c == r {
+				cost = 0
+			}
+			next := min(diag+cost, col[i+1]+1, col[i]+1)
+			diag = col[i+1]
+			col[i+1] = next
+		}
+		if col[len(t.term)] <= t.maxEdits {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *fuzzyTerm) String() string {
+	return fmt.Sprintf("%s~%d", string(t.term), t.maxEdits)
+}
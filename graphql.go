@@ -0,0 +1,515 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The /graphql endpoint answers GraphQL queries against this schema:
+//
+//	type Query {
+//		stories(list: String = "new", limit: Int = 30): [Item]
+//		search(pattern: String!, list: String = "new"): [Item]
+//		item(id: Int!): Item
+//		user(id: String!): User
+//	}
+//	type Item {
+//		id: Int, type: String, by: String, time: Int, title: String,
+//		text: String, url: String, score: Int, descendants: Int,
+//		parent: Int, kids: [Int], comments(limit: Int): [Item]
+//	}
+//	type User {
+//		id: String, created: Int, karma: Int, about: String,
+//		submitted: [Int]
+//	}
+//
+// Only what dashboards need is supported: queries with arguments,
+// variables and aliases, but no fragments, directives or mutations.
+// Queries are limited to maxQueryDepth levels of fields and to fetching
+// maxQueryItems items and users, so that nested comments cannot have a
+// single request fetch whole threads over and over.
+
+const (
+	maxQueryDepth = 8
+	maxQueryItems = 1000
+)
+
+// A gqlField is a field of a selection set.
+type gqlField struct {
+	alias, name string
+	args        map[string]any
+	selection   []*gqlField
+}
+
+// A gqlParser parses a GraphQL query document.
+type gqlParser struct {
+	s     string
+	vars  map[string]any
+	depth int // of the selection sets being parsed
+}
+
+func (p *gqlParser) skip() {
+	for len(p.s) > 0 {
+		switch c := p.s[0]; {
+		case c == '#':
+			if i := strings.IndexByte(p.s, '\n'); i >= 0 {
+				p.s = p.s[i:]
+			} else {
+				p.s = ""
+			}
+		case c == ',' || unicode.IsSpace(rune(c)):
+			p.s = p.s[1:]
+		default:
+			return
+		}
+	}
+}
+
+// accept consumes the punctuator tok, if next.
+func (p *gqlParser) accept(tok string) bool {
+	p.skip()
+	if strings.HasPrefix(p.s, tok) {
+		p.s = p.s[len(tok):]
+		return true
+	}
+	return false
+}
+
+func (p *gqlParser) expect(tok string) error {
+	if !p.accept(tok) {
+		return p.errorf("expected %q", tok)
+	}
+	return nil
+}
+
+func (p *gqlParser) errorf(format string, args ...any) error {
+	near := p.s[:min(len(p.s), 20)]
+	return fmt.Errorf("graphql: %s near %q", fmt.Sprintf(format, args...), near)
+}
+
+func (p *gqlParser) name() (string, error) {
+	p.skip()
+	i := 0
+	for i < len(p.s) && (p.s[i] == '_' || isLetter(p.s[i]) || i > 0 && '0' <= p.s[i] && p.s[i] <= '9') {
+		i++
+	}
+	if i == 0 {
+		return "", p.errorf("expected name")
+	}
+	name := p.s[:i]
+	p.s = p.s[i:]
+	return name, nil
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// document parses a query document holding a single operation.
+func (p *gqlParser) document() ([]*gqlField, error) {
+	p.skip()
+	if !strings.HasPrefix(p.s, "{") {
+		op, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if op != "query" {
+			return nil, p.errorf("unsupported operation %q", op)
+		}
+		p.skip()
+		if !strings.HasPrefix(p.s, "(") && !strings.HasPrefix(p.s, "{") {
+			if _, err := p.name(); err != nil {
+				return nil, err
+			}
+		}
+		// Variable definitions only declare types; the values are
+		// taken from the request as they are.
+		if p.accept("(") {
+			i := strings.IndexByte(p.s, ')')
+			if i < 0 {
+				return nil, p.errorf("unterminated variable definitions")
+			}
+			p.s = p.s[i+1:]
+		}
+	}
+	sel, err := p.selection()
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.s != "" {
+		return nil, p.errorf("unexpected input")
+	}
+	return sel, nil
+}
+
+func (p *gqlParser) selection() ([]*gqlField, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if p.depth++; p.depth > maxQueryDepth {
+		return nil, p.errorf("query deeper than %d levels", maxQueryDepth)
+	}
+	defer func() { p.depth-- }()
+	var fields []*gqlField
+	for !p.accept("}") {
+		if p.accept("...") {
+			return nil, p.errorf("fragments are not supported")
+		}
+		f := new(gqlField)
+		var err error
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		f.alias = f.name
+		if p.accept(":") {
+			if f.name, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if p.accept("(") {
+			f.args = make(map[string]any)
+			for !p.accept(")") {
+				arg, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if f.args[arg], err = p.value(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if p.skip(); strings.HasPrefix(p.s, "{") {
+			if f.selection, err = p.selection(); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// value parses an argument value: a variable, string, number, boolean
+// or null.
+func (p *gqlParser) value() (any, error) {
+	p.skip()
+	switch {
+	case p.accept("$"):
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return p.vars[name], nil
+	case strings.HasPrefix(p.s, `"`):
+		i := 1
+		for i < len(p.s) && p.s[i] != '"' {
+			if p.s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(p.s) {
+			return nil, p.errorf("unterminated string")
+		}
+		var s string
+		if err := json.Unmarshal([]byte(p.s[:i+1]), &s); err != nil {
+			return nil, p.errorf("invalid string")
+		}
+		p.s = p.s[i+1:]
+		return s, nil
+	case len(p.s) > 0 && (p.s[0] == '-' || '0' <= p.s[0] && p.s[0] <= '9'):
+		i := 1
+		for i < len(p.s) && strings.IndexByte("0123456789.eE+-", p.s[i]) >= 0 {
+			i++
+		}
+		n, err := strconv.ParseFloat(p.s[:i], 64)
+		if err != nil {
+			return nil, p.errorf("invalid number")
+		}
+		p.s = p.s[i:]
+		return n, nil
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return nil, p.errorf("unsupported value %s", name)
+}
+
+// stringArg and intArg return the argument name of f, or def if missing.
+func (f *gqlField) stringArg(name, def string) (string, error) {
+	switch v := f.args[name].(type) {
+	case nil:
+		return def, nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("graphql: %s: argument %s must be a string", f.name, name)
+}
+
+func (f *gqlField) intArg(name string, def int) (int, error) {
+	switch v := f.args[name].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("graphql: %s: argument %s must be an integer", f.name, name)
+}
+
+// A gqlResolver resolves the fields of a query, counting the items and
+// users it fetches against the budget of the request.
+type gqlResolver struct {
+	budget int // of items and users left to fetch
+}
+
+// fetch takes n items or users from the budget of the request.
+func (r *gqlResolver) fetch(n int) error {
+	if n > r.budget {
+		return fmt.Errorf("graphql: query fetches more than %d items", maxQueryItems)
+	}
+	r.budget -= n
+	return nil
+}
+
+// resolveQuery resolves the fields of the query root.
+func (r *gqlResolver) resolveQuery(sel []*gqlField) (map[string]any, error) {
+	data := make(map[string]any)
+	for _, f := range sel {
+		var v any
+		var err error
+		switch f.name {
+		case "stories":
+			v, err = r.resolveStories(f)
+		case "search":
+			v, err = r.resolveSearch(f)
+		case "item":
+			var id int
+			if id, err = f.intArg("id", 0); err == nil {
+				if err = r.fetch(1); err == nil {
+					var it *item
+					if it, err = getItem(id); err == nil && it.ID != 0 {
+						v, err = r.resolveItem(it, f.selection)
+					}
+				}
+			}
+		case "user":
+			var id string
+			if id, err = f.stringArg("id", ""); err == nil {
+				if err = r.fetch(1); err == nil {
+					var u *user
+					if u, err = getUser(id); err == nil && u != nil {
+						v, err = resolveUser(u, f.selection)
+					}
+				}
+			}
+		case "__typename":
+			v = "Query"
+		default:
+			err = fmt.Errorf("graphql: no field %s on Query", f.name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		data[f.alias] = v
+	}
+	return data, nil
+}
+
+func (r *gqlResolver) resolveStories(f *gqlField) (any, error) {
+	list, err := f.stringArg("list", "new")
+	if err != nil {
+		return nil, err
+	}
+	limit, err := f.intArg("limit", frontPageSize)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := getStories(list)
+	if err != nil {
+		return nil, err
+	}
+	ids = ids[:max(0, min(len(ids), limit))]
+	if err := r.fetch(len(ids)); err != nil {
+		return nil, err
+	}
+	items, err := getItems(ids)
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveItems(items, f.selection)
+}
+
+func (r *gqlResolver) resolveSearch(f *gqlField) (any, error) {
+	pattern, err := f.stringArg("pattern", "")
+	if err != nil {
+		return nil, err
+	}
+	list, err := f.stringArg("list", "new")
+	if err != nil {
+		return nil, err
+	}
+	result, err := runSearch(savedSearch{Pattern: pattern, List: list})
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveItems(result.Items, f.selection)
+}
+
+func (r *gqlResolver) resolveItems(items []item, sel []*gqlField) ([]any, error) {
+	vs := make([]any, 0, len(items))
+	for i := range items {
+		v, err := r.resolveItem(&items[i], sel)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+func (r *gqlResolver) resolveItem(it *item, sel []*gqlField) (map[string]any, error) {
+	if sel == nil {
+		return nil, fmt.Errorf("graphql: Item needs a selection of fields")
+	}
+	obj := make(map[string]any)
+	for _, f := range sel {
+		var v any
+		switch f.name {
+		case "id":
+			v = it.ID
+		case "type":
+			v = it.Type
+		case "by":
+			v = it.By
+		case "time":
+			v = it.Time
+		case "title":
+			v = it.PlainTitle()
+		case "text":
+			v = it.Text
+		case "url":
+			v = it.URL
+		case "score":
+			v = it.Score
+		case "descendants":
+			v = it.Descendants
+		case "parent":
+			v = it.Parent
+		case "kids":
+			v = it.Kids
+		case "comments":
+			limit, err := f.intArg("limit", len(it.Kids))
+			if err != nil {
+				return nil, err
+			}
+			ids := it.Kids[:max(0, min(len(it.Kids), limit))]
+			if err := r.fetch(len(ids)); err != nil {
+				return nil, err
+			}
+			kids, err := getItems(ids)
+			if err != nil {
+				return nil, err
+			}
+			if v, err = r.resolveItems(kids, f.selection); err != nil {
+				return nil, err
+			}
+		case "__typename":
+			v = "Item"
+		default:
+			return nil, fmt.Errorf("graphql: no field %s on Item", f.name)
+		}
+		obj[f.alias] = v
+	}
+	return obj, nil
+}
+
+func resolveUser(u *user, sel []*gqlField) (map[string]any, error) {
+	if sel == nil {
+		return nil, fmt.Errorf("graphql: User needs a selection of fields")
+	}
+	obj := make(map[string]any)
+	for _, f := range sel {
+		var v any
+		switch f.name {
+		case "id":
+			v = u.ID
+		case "created":
+			v = u.Created
+		case "karma":
+			v = u.Karma
+		case "about":
+			v = u.About
+		case "submitted":
+			v = u.Submitted
+		case "__typename":
+			v = "User"
+		default:
+			return nil, fmt.Errorf("graphql: no field %s on User", f.name)
+		}
+		obj[f.alias] = v
+	}
+	return obj, nil
+}
+
+// serveGraphQL answers a GraphQL request, sent either as a POST of a JSON
+// object with the query and variables, of up to maxRequestBody bytes, or
+// as a GET with a query parameter.
+func serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Query = r.URL.Query().Get("query")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	type gqlError struct {
+		Message string `json:"message"`
+	}
+	var resp struct {
+		Data   map[string]any `json:"data"`
+		Errors []gqlError     `json:"errors,omitempty"`
+	}
+	p := &gqlParser{s: req.Query, vars: req.Variables}
+	sel, err := p.document()
+	if err == nil {
+		resp.Data, err = (&gqlResolver{budget: maxQueryItems}).resolveQuery(sel)
+	}
+	if err != nil {
+		resp.Errors = append(resp.Errors, gqlError{err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&resp)
+}
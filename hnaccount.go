@@ -0,0 +1,312 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hnSite is the Hacker News site, which, unlike the API, knows about
+// accounts: what they favorited, hid and voted for.
+const hnSite = "https://news.ycombinator.com"
+
+// hnPageInterval is the time waited between pages of the site, which
+// throttles clients that ask too often.
+const hnPageInterval = time.Second
+
+// maxHNPages bounds the pages of a list read from the site, 30 stories
+// each.
+const maxHNPages = 20
+
+// An hnSession is a session on the Hacker News site, logged in with the
+// account in the configuration file.
+type hnSession struct {
+	user   string
+	client *http.Client
+	last   time.Time // of the last page read
+}
+
+// hnLogin logs in to the Hacker News site with the user cookie of a
+// session in the configuration file, or else with the username and
+// password there.
+func hnLogin(c *config) (*hnSession, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &hnSession{client: &http.Client{Jar: jar}}
+	site, _ := url.Parse(hnSite)
+	cookie := c.HN.Cookie
+	if cookie == "" {
+		cookie = os.Getenv("HN_COOKIE")
+	}
+	if cookie != "" {
+		// The cookie is the name of the user, & and a secret.
+		user, _, ok := strings.Cut(cookie, "&")
+		if !ok || user == "" {
+			return nil, errors.New("invalid hn.cookie: want the user cookie of a session, such as name&secret")
+		}
+		jar.SetCookies(site, []*http.Cookie{{Name: "user", Value: cookie}})
+		s.user = user
+		return s, nil
+	}
+	username, password := c.HN.Username, c.HN.Password
+	if username == "" {
+		username, password = os.Getenv("HN_USERNAME"), os.Getenv("HN_PASSWORD")
+	}
+	if username == "" {
+		return nil, errors.New("no Hacker News account: set hn.cookie or hn.username in the config file, or $HN_COOKIE or $HN_USERNAME")
+	}
+	form := url.Values{"acct": {username}, "pw": {password}, "goto": {"news"}}
+	resp, err := s.client.PostForm(hnSite+"/login", form)
+	if err != nil {
+		return nil, fmt.Errorf("login: %v", err)
+	}
+	resp.Body.Close()
+	for _, c := range jar.Cookies(site) {
+		if c.Name == "user" {
+			s.user = username
+			return s, nil
+		}
+	}
+	return nil, errors.New("login: invalid username or password, or the site asks for a captcha; set hn.cookie instead")
+}
+
+// get reads the page of the site at path, waiting hnPageInterval since
+// the last one.
+func (s *hnSession) get(path string) (string, error) {
+	if wait := hnPageInterval - time.Since(s.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	s.last = time.Now()
+	resp, err := s.client.Get(hnSite + path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", path, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+// hnStoryRow matches the rows of the stories listed in pages of the site,
+// capturing their IDs, and hnOnStory the links of comments to the story
+// they are on, in the threads of users.
+var (
+	hnStoryRow = regexp.MustCompile(`<tr class=['"]athing[^'"]*['"] id=['"](\d+)['"]`)
+	hnOnStory  = regexp.MustCompile(`class=['"]onstory['"][^>]*>[^<]*<a href=['"]item\?id=(\d+)['"]`)
+)
+
+// hnMoreLink matches the More link of the pages of the site, capturing the
+// path of the next page.
+var hnMoreLink = regexp.MustCompile(`<a href=['"]([^'"]+)['"] class=['"]morelink['"]`)
+
+// list returns the IDs that ids matches in the page of the site at path,
+// such as /hidden, following its More links for up to maxHNPages pages.
+// Repeated IDs are listed once.
+func (s *hnSession) list(path string, ids *regexp.Regexp) ([]int, error) {
+	var list []int
+	seen := make(map[int]bool)
+	for range maxHNPages {
+		page, err := s.get(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range ids.FindAllStringSubmatch(page, -1) {
+			id, _ := strconv.Atoi(m[1])
+			if !seen[id] {
+				seen[id] = true
+				list = append(list, id)
+			}
+		}
+		m := hnMoreLink.FindStringSubmatch(page)
+		if m == nil {
+			break
+		}
+		path = "/" + html.UnescapeString(m[1])
+	}
+	return list, nil
+}
+
+// favorites returns the IDs of the stories user favorited, or, if user is
+// empty, those the user of s did.
+func (s *hnSession) favorites(user string) ([]int, error) {
+	if user == "" {
+		user = s.user
+	}
+	return s.list("/favorites?id="+url.QueryEscape(user), hnStoryRow)
+}
+
+// hidden returns the IDs of the stories the user of s hid.
+func (s *hnSession) hidden() ([]int, error) {
+	return s.list("/hidden", hnStoryRow)
+}
+
+// seen returns the IDs of the stories the user of s upvoted or commented
+// on.
+func (s *hnSession) seen() ([]int, error) {
+	upvoted, err := s.list("/upvoted?id="+url.QueryEscape(s.user), hnStoryRow)
+	if err != nil {
+		return nil, err
+	}
+	commented, err := s.list("/threads?id="+url.QueryEscape(s.user), hnOnStory)
+	if err != nil {
+		return nil, err
+	}
+	return append(upvoted, commented...), nil
+}
+
+// An hnAction is something done to a story on the site with the links of
+// its page, which carry the auth token of the session: upvote or
+// favorite. The link is missing if it was done already.
+type hnAction struct {
+	name string
+	link *regexp.Regexp // of the page of the story, capturing the token
+	path string         // format of the path done with, given the ID and token
+}
+
+var hnActions = map[string]hnAction{
+	"upvote": {
+		name: "upvote",
+		link: regexp.MustCompile(`href=['"]vote\?id=(\d+)&amp;how=up&amp;auth=([0-9a-f]+)`),
+		path: "/vote?id=%d&how=up&auth=%s&goto=news",
+	},
+	"favorite": {
+		name: "favorite",
+		link: regexp.MustCompile(`href=['"]fave\?id=(\d+)&amp;auth=([0-9a-f]+)['"]`),
+		path: "/fave?id=%d&auth=%s",
+	},
+}
+
+// do does the actions to the story id, except those done already, or that
+// cannot be, as upvoting stories of one's own.
+func (s *hnSession) do(id int, actions []hnAction) error {
+	page, err := s.get("/item?id=" + strconv.Itoa(id))
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(page, "logout") {
+		return errors.New("not logged in: the session may have expired")
+	}
+	for _, a := range actions {
+		for _, m := range a.link.FindAllStringSubmatch(page, -1) {
+			if m[1] != strconv.Itoa(id) {
+				continue
+			}
+			if _, err := s.get(fmt.Sprintf(a.path, id, m[2])); err != nil {
+				return fmt.Errorf("%s: %v", a.name, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// actOnMatches upvotes or favorites the matches of r on the site, as
+// -upvote and -favorite say, logging in with the account in the
+// configuration file. Failures are noted for -error-summary.
+func actOnMatches(r *searchResult) error {
+	var actions []hnAction
+	if *upvote {
+		actions = append(actions, hnActions["upvote"])
+	}
+	if *favorite {
+		actions = append(actions, hnActions["favorite"])
+	}
+	if len(actions) == 0 || len(r.Items) == 0 {
+		return nil
+	}
+	s, err := hnLogin(cfg())
+	if err != nil {
+		return err
+	}
+	for i := range r.Items {
+		if err := s.do(r.Items[i].ID, actions); err != nil {
+			noteError("account", r.Items[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// excludedStories are the stories of the account on the site left out of
+// searches: those hidden, with -exclude-hidden, and those upvoted or
+// commented on, with -unseen-only; see item.hidden.
+var excludedStories map[int]bool
+
+// loadExcludedStories logs in to the site and sets excludedStories.
+func loadExcludedStories() error {
+	s, err := hnLogin(cfg())
+	if err != nil {
+		return err
+	}
+	excludedStories = make(map[int]bool)
+	if *noHidden {
+		ids, err := s.hidden()
+		if err != nil {
+			return fmt.Errorf("hidden stories: %v", err)
+		}
+		for _, id := range ids {
+			excludedStories[id] = true
+		}
+	}
+	if *unseenOnly {
+		ids, err := s.seen()
+		if err != nil {
+			return fmt.Errorf("stories seen: %v", err)
+		}
+		for _, id := range ids {
+			excludedStories[id] = true
+		}
+	}
+	return nil
+}
+
+// runAccountList runs the favorites and hidden commands, which list the
+// stories the user favorited, or hid, on the site, as the user command
+// lists recent items. The favorites command takes the name of the user,
+// that of the account in the configuration file by default.
+func runAccountList(cmd string, args []string) {
+	if cmd == "favorites" && len(args) > 1 || cmd == "hidden" && len(args) > 0 {
+		fatal(fmt.Errorf("%s: too many arguments", cmd))
+	}
+	// The favorites of others are public.
+	s := &hnSession{client: http.DefaultClient}
+	var err error
+	if cmd == "hidden" || len(args) == 0 {
+		if s, err = hnLogin(cfg()); err != nil {
+			fatal(fmt.Errorf("%s: %v", cmd, err))
+		}
+	}
+	var ids []int
+	if cmd == "favorites" {
+		ids, err = s.favorites(strings.Join(args, ""))
+	} else {
+		ids, err = s.hidden()
+	}
+	if err != nil {
+		fatal(fmt.Errorf("%s: %v", cmd, err))
+	}
+	items, err := getItems(ids)
+	if err != nil {
+		fatal(err)
+	}
+	r := &searchResult{Total: len(items), Items: items}
+	if err := writeItems(savedSearch{Name: cmd}, r); err != nil {
+		fatal(err)
+	}
+}
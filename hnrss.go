@@ -0,0 +1,220 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// The hnrss routes serve feeds under the URL scheme of hnrss.org, so that
+// its users can point their feed readers to a self-hosted server instead.
+// They take the hnrss parameters q (words the title or text must contain),
+// points and comments (minimum counts), and count (number of entries).
+func addHNRSSRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /newest", hnrssList("new", "Hacker News: Newest"))
+	mux.HandleFunc("GET /best", hnrssList("best", "Hacker News: Best"))
+	mux.HandleFunc("GET /frontpage", hnrssFrontPage)
+	mux.HandleFunc("GET /user", hnrssUser)
+}
+
+const (
+	hnrssCount    = 20  // entries per feed, by default
+	hnrssMaxCount = 100 // most entries per feed
+)
+
+// hnrssParams are the feed parameters of an hnrss request.
+type hnrssParams struct {
+	re       *pattern // from q; nil matches everything
+	points   int
+	comments int
+	count    int
+}
+
+func parseHNRSSParams(r *http.Request) (*hnrssParams, error) {
+	q := r.URL.Query()
+	p := &hnrssParams{count: hnrssCount}
+	if s := q.Get("q"); s != "" {
+		var err error
+		if p.re, err = compile("(?i)" + regexp.QuoteMeta(s)); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range []struct {
+		name string
+		v    *int
+	}{{"points", &p.points}, {"comments", &p.comments}, {"count", &p.count}} {
+		s := q.Get(f.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s %q", f.name, s)
+		}
+		*f.v = n
+	}
+	p.count = min(p.count, hnrssMaxCount)
+	return p, nil
+}
+
+// filter returns the items that meet the parameters, at most count of them.
+func (p *hnrssParams) filter(items []item) []item {
+	var keep []item
+	for _, it := range items {
+		if len(keep) == p.count {
+			break
+		}
+		if it.Score < p.points || it.Descendants < p.comments {
+			continue
+		}
+		if p.re != nil && !it.matches(p.re) {
+			continue
+		}
+		keep = append(keep, it)
+	}
+	return keep
+}
+
+func hnrssList(list, title string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := parseHNRSSParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := search(list, p.re)
+		if err != nil {
+			hnrssError(w, err)
+			return
+		}
+		if list == "best" {
+			slices.SortFunc(result.Items, func(a, b item) int { return cmp.Compare(b.Score, a.Score) })
+		} else {
+			slices.SortFunc(result.Items, func(a, b item) int { return cmp.Compare(b.Time, a.Time) })
+		}
+		serveRSS(w, title, title, p.filter(result.Items))
+	}
+}
+
+func hnrssFrontPage(w http.ResponseWriter, r *http.Request) {
+	p, err := parseHNRSSParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ids, err := getStories("top")
+	if err != nil {
+		hnrssError(w, err)
+		return
+	}
+	items, err := getItems(ids[:min(len(ids), frontPageSize)])
+	if err != nil {
+		hnrssError(w, err)
+		return
+	}
+	serveRSS(w, "Hacker News: Front Page", "Hacker News RSS", p.filter(items))
+}
+
+func hnrssUser(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+	p, err := parseHNRSSParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	u, err := getUser(id)
+	if err != nil {
+		hnrssError(w, err)
+		return
+	}
+	if u == nil {
+		http.Error(w, "no such user", http.StatusNotFound)
+		return
+	}
+	// Only the newest submissions can make it into the feed, but some
+	// may be dropped by the filters, so fetch a few more than needed.
+	items, err := getItems(u.Submitted[:min(len(u.Submitted), 2*p.count)])
+	if err != nil {
+		hnrssError(w, err)
+		return
+	}
+	for i := range items {
+		if items[i].Type == "comment" {
+			items[i].Title = "New comment by " + items[i].By
+		}
+	}
+	title := "Hacker News: " + id + " submissions and comments"
+	serveRSS(w, title, title, p.filter(items))
+}
+
+func serveRSS(w http.ResponseWriter, title, description string, items []item) {
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	if err := writeRSS(w, title, description, items); err != nil {
+		log.Print(err)
+	}
+}
+
+func hnrssError(w http.ResponseWriter, err error) {
+	log.Print(err)
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
+
+// getItems fetches the items with the given ids, in the same order.
+// Deleted items are left out.
+func getItems(ids []int) ([]item, error) {
+	items := make([]item, len(ids))
+	errc := make(chan error, len(ids))
+	for i, id := range ids {
+		go func() {
+			it, err := getItem(id)
+			if err == nil {
+				items[i] = *it
+			}
+			errc <- err
+		}()
+	}
+	for range ids {
+		if err := <-errc; err != nil {
+			return nil, err
+		}
+	}
+	return slices.DeleteFunc(items, func(it item) bool { return it.Deleted || it.ID == 0 }), nil
+}
+
+// A user is a Hacker News user, as returned by the API.
+type user struct {
+	ID        string `json:"id"`
+	Created   int    `json:"created"`
+	Karma     int    `json:"karma"`
+	About     string `json:"about"` // HTML
+	Submitted []int  `json:"submitted"`
+}
+
+// getUser fetches the user with the given id. It returns nil if there
+// is no such user.
+func getUser(id string) (*user, error) {
+	resp, err := http.Get(basePath + "/user/" + url.PathEscape(id) + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	defer resp.Body.Close()
+	var u *user
+	if err := json.NewDecoder(resp.Body).Decode(&u); err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	return u, nil
+}
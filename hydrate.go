@@ -0,0 +1,118 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// sharedTTL is how long the serve command reuses a fetched item or story
+// list. It is short enough for scores to stay fresh, and long enough for
+// the searches run in one cycle, such as the saved searches scheduled at
+// the same time, the watchlist and the feeds being polled, to share the
+// fetches instead of each making its own.
+const sharedTTL = time.Minute
+
+// A sharedCache holds the items and story lists fetched recently. Callers
+// asking for a value that is being fetched wait for that fetch rather than
+// starting another.
+type sharedCache struct {
+	mu      sync.Mutex
+	items   map[int]*sharedEntry[item]
+	stories map[string]*sharedEntry[[]int]
+	swept   time.Time
+}
+
+type sharedEntry[T any] struct {
+	done    chan struct{} // closed when the fetch is over
+	value   T
+	err     error
+	fetched time.Time
+}
+
+// shared is the cache used by getItem and getStories, or nil if they
+// always fetch. The serve command sets it.
+var shared *sharedCache
+
+func newSharedCache() *sharedCache {
+	return &sharedCache{
+		items:   make(map[int]*sharedEntry[item]),
+		stories: make(map[string]*sharedEntry[[]int]),
+		swept:   time.Now(),
+	}
+}
+
+// item returns the item with the given id, calling fetch unless it was
+// fetched less than sharedTTL ago.
+func (c *sharedCache) item(id int, fetch func(int) (*item, error)) (*item, error) {
+	it, err := lookup(c, c.items, id, func() (item, error) {
+		it, err := fetch(id)
+		if err != nil {
+			return item{}, err
+		}
+		return *it, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+// storyList returns the story list which, calling fetch unless it was
+// fetched less than sharedTTL ago.
+func (c *sharedCache) storyList(which string, fetch func(string) ([]int, error)) ([]int, error) {
+	return lookup(c, c.stories, which, func() ([]int, error) { return fetch(which) })
+}
+
+func lookup[K comparable, T any](c *sharedCache, m map[K]*sharedEntry[T], key K, fetch func() (T, error)) (T, error) {
+	c.mu.Lock()
+	if time.Since(c.swept) > sharedTTL {
+		c.sweep()
+	}
+	e, ok := m[key]
+	if ok {
+		select {
+		case <-e.done:
+			// Failed fetches are not reused, and neither are stale ones.
+			if e.err != nil || time.Since(e.fetched) > sharedTTL {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		e = &sharedEntry[T]{done: make(chan struct{})}
+		m[key] = e
+		c.mu.Unlock()
+		e.value, e.err = fetch()
+		e.fetched = time.Now()
+		close(e.done)
+		return e.value, e.err
+	}
+	c.mu.Unlock()
+	<-e.done
+	timings.cacheHits.Add(1)
+	return e.value, e.err
+}
+
+// sweep drops the stale entries. The caller holds c.mu.
+func (c *sharedCache) sweep() {
+	sweepMap(c.items)
+	sweepMap(c.stories)
+	c.swept = time.Now()
+}
+
+func sweepMap[K comparable, T any](m map[K]*sharedEntry[T]) {
+	for k, e := range m {
+		select {
+		case <-e.done:
+			if time.Since(e.fetched) > sharedTTL {
+				delete(m, k)
+			}
+		default:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// itemFields has the fields of item, but not its JSON methods.
+type itemFields item
+
+// itemKeys are the lower-case JSON keys that item decodes into its fields.
+var itemKeys = func() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeFor[itemFields]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		keys[strings.ToLower(name)] = true
+	}
+	return keys
+}()
+
+// UnmarshalJSON decodes an item, keeping the fields it has no place for,
+// such as those added to the API after this was written, in Extra.
+func (it *item) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*itemFields)(it)); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	it.Extra = nil
+	for k, v := range all {
+		if !itemKeys[strings.ToLower(k)] {
+			if it.Extra == nil {
+				it.Extra = make(map[string]json.RawMessage)
+			}
+			it.Extra[k] = v
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes an item with its fields followed by those in Extra,
+// so that the JSON outputs carry what the API sent.
+func (it item) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(itemFields(it))
+	if err != nil || len(it.Extra) == 0 {
+		return data, err
+	}
+	var b bytes.Buffer
+	b.Write(data[:len(data)-1]) // without the closing brace
+	for _, k := range slices.Sorted(maps.Keys(it.Extra)) {
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteByte(',')
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(it.Extra[k])
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,1045 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// -jq filters the result of a search, as JSON: {"Total": N, "Items": [...]},
+// with a jq program such as
+//
+//	.Items[] | select(.Score > 100) | .URL
+//
+// It is a subset of jq, enough to pick and reshape matches without
+// linking a full implementation in:
+//
+//	.  .Name  ."name"  .[]  .[e]  e | e  e, e  e // e
+//	e or e  e and e  == != < <= > >=  + - * / %  -e
+//	numbers, strings, true, false, null, [e], {name: e, "name": e, name}
+//	select(e) map(e) sort_by(e) limit(n; e) test(re) join(s) has(k)
+//	length keys not empty sort tostring tonumber ascii_downcase
+//	ascii_upcase now
+//
+// There are no variables, string interpolation, slices, paths or
+// user-defined functions.
+
+// A jqFilter returns the values a jq program yields for the value v.
+type jqFilter func(v any) ([]any, error)
+
+// jqProgram is the program of -jq, compiled by setup, or nil.
+var jqProgram jqFilter
+
+// A jqParser parses a jq program into a jqFilter.
+type jqParser struct {
+	s string
+}
+
+// compileJQ compiles the jq program src.
+func compileJQ(src string) (jqFilter, error) {
+	p := &jqParser{s: src}
+	f, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.s != "" {
+		return nil, p.errorf("unexpected input")
+	}
+	return f, nil
+}
+
+func (p *jqParser) skip() {
+	for len(p.s) > 0 {
+		switch c := p.s[0]; {
+		case c == '#':
+			if i := strings.IndexByte(p.s, '\n'); i >= 0 {
+				p.s = p.s[i:]
+			} else {
+				p.s = ""
+			}
+		case unicode.IsSpace(rune(c)):
+			p.s = p.s[1:]
+		default:
+			return
+		}
+	}
+}
+
+// accept consumes the token tok, if next. Keywords must not be followed
+// by other letters, as in order.
+func (p *jqParser) accept(tok string) bool {
+	p.skip()
+	if !strings.HasPrefix(p.s, tok) {
+		return false
+	}
+	if isLetter(tok[0]) && len(p.s) > len(tok) && isNameByte(p.s[len(tok)]) {
+		return false
+	}
+	p.s = p.s[len(tok):]
+	return true
+}
+
+func (p *jqParser) expect(tok string) error {
+	if !p.accept(tok) {
+		return p.errorf("expected %q", tok)
+	}
+	return nil
+}
+
+func (p *jqParser) errorf(format string, args ...any) error {
+	near := p.s[:min(len(p.s), 20)]
+	return fmt.Errorf("jq: %s near %q", fmt.Sprintf(format, args...), near)
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || isLetter(c) || '0' <= c && c <= '9'
+}
+
+// name consumes a name, if next.
+func (p *jqParser) name() string {
+	p.skip()
+	i := 0
+	for i < len(p.s) && (p.s[i] == '_' || isLetter(p.s[i]) || i > 0 && isNameByte(p.s[i])) {
+		i++
+	}
+	name := p.s[:i]
+	p.s = p.s[i:]
+	return name
+}
+
+// str consumes a string literal, if next.
+func (p *jqParser) str() (string, bool, error) {
+	p.skip()
+	if !strings.HasPrefix(p.s, `"`) {
+		return "", false, nil
+	}
+	i := 1
+	for i < len(p.s) && p.s[i] != '"' {
+		if p.s[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i >= len(p.s) {
+		return "", false, p.errorf("unterminated string")
+	}
+	var s string
+	if err := json.Unmarshal([]byte(p.s[:i+1]), &s); err != nil {
+		return "", false, p.errorf("invalid string")
+	}
+	p.s = p.s[i+1:]
+	return s, true, nil
+}
+
+// pipe parses e | e, which feeds each value the left yields to the right.
+func (p *jqParser) pipe() (jqFilter, error) {
+	f, err := p.comma()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		g, err := p.comma()
+		if err != nil {
+			return nil, err
+		}
+		f = jqCompose(f, g)
+	}
+	return f, nil
+}
+
+func jqCompose(f, g jqFilter) jqFilter {
+	return func(v any) ([]any, error) {
+		in, err := f(v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, w := range in {
+			vs, err := g(w)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, vs...)
+		}
+		return out, nil
+	}
+}
+
+// comma parses e, e, which yields the values of both.
+func (p *jqParser) comma() (jqFilter, error) {
+	f, err := p.alt()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(",") {
+		g, err := p.alt()
+		if err != nil {
+			return nil, err
+		}
+		f = func(f, g jqFilter) jqFilter {
+			return func(v any) ([]any, error) {
+				a, err := f(v)
+				if err != nil {
+					return nil, err
+				}
+				b, err := g(v)
+				return append(a, b...), err
+			}
+		}(f, g)
+	}
+	return f, nil
+}
+
+// alt parses e // e, which yields the values of the left that are
+// neither false nor null or, if there are none, those of the right.
+func (p *jqParser) alt() (jqFilter, error) {
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("//") {
+		g, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		f = func(f, g jqFilter) jqFilter {
+			return func(v any) ([]any, error) {
+				a, err := f(v)
+				if err != nil {
+					return nil, err
+				}
+				a = slices.DeleteFunc(a, func(v any) bool { return !jqTruthy(v) })
+				if len(a) > 0 {
+					return a, nil
+				}
+				return g(v)
+			}
+		}(f, g)
+	}
+	return f, nil
+}
+
+func (p *jqParser) or() (jqFilter, error) {
+	f, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		g, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		f = jqLogical(f, g, true)
+	}
+	return f, nil
+}
+
+func (p *jqParser) and() (jqFilter, error) {
+	f, err := p.compare()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		g, err := p.compare()
+		if err != nil {
+			return nil, err
+		}
+		f = jqLogical(f, g, false)
+	}
+	return f, nil
+}
+
+// jqLogical returns the filter f or g, if or is set, or else f and g. As
+// in jq, g is only evaluated for the outputs of f that do not decide the
+// result, so that guards such as .x != null and (.x|test("y")) work.
+func jqLogical(f, g jqFilter, or bool) jqFilter {
+	return func(v any) ([]any, error) {
+		as, err := f(v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, a := range as {
+			if jqTruthy(a) == or {
+				out = append(out, or)
+				continue
+			}
+			bs, err := g(v)
+			if err != nil {
+				return nil, err
+			}
+			for _, b := range bs {
+				out = append(out, jqTruthy(b))
+			}
+		}
+		return out, nil
+	}
+}
+
+// jqComparisons are the comparison operators, longest first.
+var jqComparisons = []struct {
+	op string
+	ok func(c int) bool
+}{
+	{"==", func(c int) bool { return c == 0 }},
+	{"!=", func(c int) bool { return c != 0 }},
+	{"<=", func(c int) bool { return c <= 0 }},
+	{">=", func(c int) bool { return c >= 0 }},
+	{"<", func(c int) bool { return c < 0 }},
+	{">", func(c int) bool { return c > 0 }},
+}
+
+func (p *jqParser) compare() (jqFilter, error) {
+	f, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range jqComparisons {
+		if p.accept(c.op) {
+			g, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			return jqBinary(f, g, func(a, b any) (any, error) { return c.ok(jqCompare(a, b)), nil }), nil
+		}
+	}
+	return f, nil
+}
+
+func (p *jqParser) additive() (jqFilter, error) {
+	f, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept("+"):
+			op = '+'
+		case p.accept("-"):
+			op = '-'
+		default:
+			return f, nil
+		}
+		g, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		f = jqBinary(f, g, func(a, b any) (any, error) { return jqArith(op, a, b) })
+	}
+}
+
+func (p *jqParser) multiplicative() (jqFilter, error) {
+	f, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		p.skip()
+		switch {
+		case p.accept("*"):
+			op = '*'
+		case !strings.HasPrefix(p.s, "//") && p.accept("/"):
+			op = '/'
+		case p.accept("%"):
+			op = '%'
+		default:
+			return f, nil
+		}
+		g, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		f = jqBinary(f, g, func(a, b any) (any, error) { return jqArith(op, a, b) })
+	}
+}
+
+// jqBinary applies op to each pair of values f and g yield for a value.
+func jqBinary(f, g jqFilter, op func(a, b any) (any, error)) jqFilter {
+	return func(v any) ([]any, error) {
+		as, err := f(v)
+		if err != nil {
+			return nil, err
+		}
+		bs, err := g(v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, b := range bs {
+			for _, a := range as {
+				r, err := op(a, b)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, r)
+			}
+		}
+		return out, nil
+	}
+}
+
+// postfix parses a primary expression followed by any number of .name,
+// ."name", [] and [e].
+func (p *jqParser) postfix() (jqFilter, error) {
+	f, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skip()
+		switch {
+		case strings.HasPrefix(p.s, ".") && len(p.s) > 1 && (isLetter(p.s[1]) || p.s[1] == '_' || p.s[1] == '"' || p.s[1] == '['):
+			p.s = p.s[1:]
+			g, err := p.field()
+			if err != nil {
+				return nil, err
+			}
+			f = jqCompose(f, g)
+		case strings.HasPrefix(p.s, "["):
+			g, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			f = jqCompose(f, g)
+		default:
+			return f, nil
+		}
+	}
+}
+
+// field parses what follows a dot: a name, a string or an index.
+func (p *jqParser) field() (jqFilter, error) {
+	if strings.HasPrefix(p.s, "[") {
+		return p.index()
+	}
+	key, ok, err := p.str()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		key = p.name()
+	}
+	return func(v any) ([]any, error) {
+		r, err := jqIndex(v, key)
+		return []any{r}, err
+	}, nil
+}
+
+// index parses [] or [e].
+func (p *jqParser) index() (jqFilter, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	if p.accept("]") {
+		return jqIterate, nil
+	}
+	g, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return func(v any) ([]any, error) {
+		keys, err := g(v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, k := range keys {
+			r, err := jqIndex(v, k)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+		return out, nil
+	}, nil
+}
+
+func jqIndex(v, key any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		if k, ok := key.(string); ok {
+			return v[k], nil
+		}
+	case []any:
+		if n, ok := key.(float64); ok {
+			i := int(n)
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return nil, nil
+			}
+			return v[i], nil
+		}
+	}
+	return nil, fmt.Errorf("jq: cannot index %s with %s", jqType(v), jqJSON(key))
+}
+
+func jqIterate(v any) ([]any, error) {
+	switch v := v.(type) {
+	case []any:
+		return v, nil
+	case map[string]any:
+		var out []any
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			out = append(out, v[k])
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("jq: cannot iterate over %s", jqType(v))
+}
+
+func (p *jqParser) primary() (jqFilter, error) {
+	p.skip()
+	switch {
+	case p.accept("("):
+		f, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	case p.accept("["):
+		if p.accept("]") {
+			return func(any) ([]any, error) { return []any{[]any{}}, nil }, nil
+		}
+		f, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return func(v any) ([]any, error) {
+			vs, err := f(v)
+			if vs == nil {
+				vs = []any{}
+			}
+			return []any{vs}, err
+		}, nil
+	case p.accept("{"):
+		return p.object()
+	case p.accept("-"):
+		f, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		return jqBinary(func(any) ([]any, error) { return []any{0.0}, nil }, f, func(a, b any) (any, error) { return jqArith('-', a, b) }), nil
+	case p.accept("."):
+		if len(p.s) > 0 && (isLetter(p.s[0]) || p.s[0] == '_' || p.s[0] == '"') {
+			return p.field()
+		}
+		return func(v any) ([]any, error) { return []any{v}, nil }, nil
+	case len(p.s) > 0 && '0' <= p.s[0] && p.s[0] <= '9':
+		i := 1
+		for i < len(p.s) && (strings.IndexByte("0123456789.eE", p.s[i]) >= 0 ||
+			(p.s[i] == '+' || p.s[i] == '-') && (p.s[i-1] == 'e' || p.s[i-1] == 'E')) {
+			i++
+		}
+		n, err := strconv.ParseFloat(p.s[:i], 64)
+		if err != nil {
+			return nil, p.errorf("invalid number")
+		}
+		p.s = p.s[i:]
+		return func(any) ([]any, error) { return []any{n}, nil }, nil
+	}
+	if s, ok, err := p.str(); ok || err != nil {
+		return func(any) ([]any, error) { return []any{s}, nil }, err
+	}
+	name := p.name()
+	if name == "" {
+		return nil, p.errorf("expected an expression")
+	}
+	var args []jqFilter
+	if p.accept("(") {
+		for {
+			f, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, f)
+			if !p.accept(";") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	return p.call(name, args)
+}
+
+// object parses the rest of {name: e, "name": e, name}, whose values are
+// parsed without commas, as in jq.
+func (p *jqParser) object() (jqFilter, error) {
+	type entry struct {
+		key   string
+		value jqFilter
+	}
+	var entries []entry
+	for !p.accept("}") {
+		if len(entries) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		key, ok, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if key = p.name(); key == "" {
+				return nil, p.errorf("expected a key")
+			}
+		}
+		var value jqFilter
+		if p.accept(":") {
+			if value, err = p.alt(); err != nil {
+				return nil, err
+			}
+		} else {
+			k := key
+			value = func(v any) ([]any, error) {
+				r, err := jqIndex(v, k)
+				return []any{r}, err
+			}
+		}
+		entries = append(entries, entry{key, value})
+	}
+	return func(v any) ([]any, error) {
+		// Each combination of the values of the entries makes an object.
+		out := []any{map[string]any{}}
+		for _, e := range entries {
+			vs, err := e.value(v)
+			if err != nil {
+				return nil, err
+			}
+			var next []any
+			for _, o := range out {
+				for _, x := range vs {
+					m := maps.Clone(o.(map[string]any))
+					m[e.key] = x
+					next = append(next, m)
+				}
+			}
+			out = next
+		}
+		return out, nil
+	}, nil
+}
+
+// call returns the filter of the builtin name applied to args.
+func (p *jqParser) call(name string, args []jqFilter) (jqFilter, error) {
+	// Builtins that map each value to one.
+	simple := map[string]func(v any) (any, error){
+		"true":           func(any) (any, error) { return true, nil },
+		"false":          func(any) (any, error) { return false, nil },
+		"null":           func(any) (any, error) { return nil, nil },
+		"not":            func(v any) (any, error) { return !jqTruthy(v), nil },
+		"now":            func(any) (any, error) { return float64(time.Now().Unix()), nil },
+		"length":         jqLength,
+		"keys":           jqKeys,
+		"tostring":       jqToString,
+		"tonumber":       jqToNumber,
+		"ascii_downcase": func(v any) (any, error) { return jqMapString(v, strings.ToLower) },
+		"ascii_upcase":   func(v any) (any, error) { return jqMapString(v, strings.ToUpper) },
+		"sort": func(v any) (any, error) {
+			a, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("jq: cannot sort %s", jqType(v))
+			}
+			a = slices.Clone(a)
+			slices.SortStableFunc(a, jqCompare)
+			return a, nil
+		},
+	}
+	if f, ok := simple[name]; ok && len(args) == 0 {
+		return func(v any) ([]any, error) {
+			r, err := f(v)
+			return []any{r}, err
+		}, nil
+	}
+	arity := map[string]int{"empty": 0, "select": 1, "map": 1, "sort_by": 1, "test": 1, "join": 1, "has": 1, "limit": 2}
+	if n, ok := arity[name]; !ok || n != len(args) {
+		return nil, p.errorf("unknown function %s/%d", name, len(args))
+	}
+	switch name {
+	case "empty":
+		return func(any) ([]any, error) { return nil, nil }, nil
+	case "select":
+		return func(v any) ([]any, error) {
+			conds, err := args[0](v)
+			if err != nil {
+				return nil, err
+			}
+			var out []any
+			for _, c := range conds {
+				if jqTruthy(c) {
+					out = append(out, v)
+				}
+			}
+			return out, nil
+		}, nil
+	case "map":
+		return func(v any) ([]any, error) {
+			elems, err := jqIterate(v)
+			if err != nil {
+				return nil, err
+			}
+			out := []any{}
+			for _, e := range elems {
+				vs, err := args[0](e)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, vs...)
+			}
+			return []any{out}, nil
+		}, nil
+	case "sort_by":
+		return func(v any) ([]any, error) {
+			a, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("jq: cannot sort %s", jqType(v))
+			}
+			keys := make([]any, len(a))
+			for i, e := range a {
+				k, err := args[0](e)
+				if err != nil {
+					return nil, err
+				}
+				keys[i] = k
+			}
+			idx := make([]int, len(a))
+			for i := range idx {
+				idx[i] = i
+			}
+			slices.SortStableFunc(idx, func(i, j int) int { return jqCompare(keys[i], keys[j]) })
+			sorted := make([]any, len(a))
+			for i, j := range idx {
+				sorted[i] = a[j]
+			}
+			return []any{sorted}, nil
+		}, nil
+	case "limit":
+		return func(v any) ([]any, error) {
+			ns, err := args[0](v)
+			if err != nil {
+				return nil, err
+			}
+			vs, err := args[1](v)
+			if err != nil {
+				return nil, err
+			}
+			var out []any
+			for _, n := range ns {
+				f, ok := n.(float64)
+				if !ok {
+					return nil, fmt.Errorf("jq: limit needs a number, not %s", jqType(n))
+				}
+				out = append(out, vs[:max(0, min(int(f), len(vs)))]...)
+			}
+			return out, nil
+		}, nil
+	}
+	// The rest take the values of their argument.
+	op := map[string]func(v, arg any) (any, error){
+		"test": func(v, arg any) (any, error) {
+			s, ok1 := v.(string)
+			expr, ok2 := arg.(string)
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("jq: cannot test %s against %s", jqType(v), jqType(arg))
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("jq: %v", err)
+			}
+			return re.MatchString(s), nil
+		},
+		"join": func(v, arg any) (any, error) {
+			a, ok1 := v.([]any)
+			sep, ok2 := arg.(string)
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("jq: cannot join %s with %s", jqType(v), jqType(arg))
+			}
+			parts := make([]string, len(a))
+			for i, e := range a {
+				if e != nil {
+					s, err := jqToString(e)
+					if err != nil {
+						return nil, err
+					}
+					parts[i] = s.(string)
+				}
+			}
+			return strings.Join(parts, sep), nil
+		},
+		"has": func(v, arg any) (any, error) {
+			switch v := v.(type) {
+			case map[string]any:
+				if k, ok := arg.(string); ok {
+					_, has := v[k]
+					return has, nil
+				}
+			case []any:
+				if n, ok := arg.(float64); ok {
+					return n >= 0 && int(n) < len(v), nil
+				}
+			}
+			return nil, fmt.Errorf("jq: cannot check whether %s has %s", jqType(v), jqJSON(arg))
+		},
+	}[name]
+	return func(v any) ([]any, error) {
+		as, err := args[0](v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, a := range as {
+			r, err := op(v, a)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+		return out, nil
+	}, nil
+}
+
+func jqTruthy(v any) bool {
+	return v != nil && v != false
+}
+
+func jqType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+func jqJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// jqCompare orders values as jq does: null, false, true, numbers,
+// strings, arrays and objects, each kind in its natural order.
+func jqCompare(a, b any) int {
+	rank := func(v any) int {
+		switch v {
+		case nil:
+			return 0
+		case false:
+			return 1
+		case true:
+			return 2
+		}
+		return slices.Index([]string{"number", "string", "array", "object"}, jqType(v)) + 3
+	}
+	if c := cmp.Compare(rank(a), rank(b)); c != 0 {
+		return c
+	}
+	switch a := a.(type) {
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case []any:
+		return slices.CompareFunc(a, b.([]any), jqCompare)
+	case map[string]any:
+		b := b.(map[string]any)
+		ka, kb := slices.Sorted(maps.Keys(a)), slices.Sorted(maps.Keys(b))
+		if c := slices.Compare(ka, kb); c != 0 {
+			return c
+		}
+		for _, k := range ka {
+			if c := jqCompare(a[k], b[k]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func jqArith(op byte, a, b any) (any, error) {
+	x, ok1 := a.(float64)
+	y, ok2 := b.(float64)
+	if ok1 && ok2 {
+		switch op {
+		case '+':
+			return x + y, nil
+		case '-':
+			return x - y, nil
+		case '*':
+			return x * y, nil
+		case '/':
+			if y == 0 {
+				return nil, fmt.Errorf("jq: %v divided by zero", x)
+			}
+			return x / y, nil
+		case '%':
+			if int(y) == 0 {
+				return nil, fmt.Errorf("jq: %v modulo zero", x)
+			}
+			return float64(int(x) % int(y)), nil
+		}
+	}
+	switch {
+	case op == '+' && a == nil:
+		return b, nil
+	case op == '+' && b == nil:
+		return a, nil
+	}
+	switch a := a.(type) {
+	case string:
+		if s, ok := b.(string); ok {
+			switch op {
+			case '+':
+				return a + s, nil
+			case '/':
+				var out []any
+				for _, part := range strings.Split(a, s) {
+					out = append(out, part)
+				}
+				return out, nil
+			}
+		}
+	case []any:
+		if c, ok := b.([]any); ok {
+			switch op {
+			case '+':
+				return append(slices.Clone(a), c...), nil
+			case '-':
+				return slices.DeleteFunc(slices.Clone(a), func(e any) bool {
+					return slices.ContainsFunc(c, func(f any) bool { return jqCompare(e, f) == 0 })
+				}), nil
+			}
+		}
+	case map[string]any:
+		if m, ok := b.(map[string]any); ok && op == '+' {
+			r := maps.Clone(a)
+			maps.Copy(r, m)
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("jq: %s and %s cannot be %s", jqType(a), jqType(b), map[byte]string{
+		'+': "added", '-': "subtracted", '*': "multiplied", '/': "divided", '%': "divided",
+	}[op])
+}
+
+func jqLength(v any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0.0, nil
+	case float64:
+		return math.Abs(v), nil
+	case string:
+		return float64(utf8.RuneCountInString(v)), nil
+	case []any:
+		return float64(len(v)), nil
+	case map[string]any:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("jq: %s has no length", jqType(v))
+}
+
+func jqKeys(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		var keys []any
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			keys = append(keys, k)
+		}
+		return keys, nil
+	case []any:
+		keys := make([]any, len(v))
+		for i := range keys {
+			keys[i] = float64(i)
+		}
+		return keys, nil
+	}
+	return nil, fmt.Errorf("jq: %s has no keys", jqType(v))
+}
+
+func jqToString(v any) (any, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return jqJSON(v), nil
+}
+
+func jqToNumber(v any) (any, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("jq: cannot parse %s as a number", jqJSON(v))
+}
+
+func jqMapString(v any, f func(string) string) (any, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("jq: %s is not a string", jqType(v))
+	}
+	return f(s), nil
+}
+
+// writeJQ writes the values f yields for r to w, one a line: strings as
+// they are, like jq -r, and the rest as JSON.
+func writeJQ(w io.Writer, f jqFilter, r *searchResult) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	out, err := f(v)
+	if err != nil {
+		return err
+	}
+	for _, o := range out {
+		s, ok := o.(string)
+		if !ok {
+			s = jqJSON(o)
+		}
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
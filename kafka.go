@@ -0,0 +1,433 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// kafkaTimeout bounds the time to connect to the brokers and publish.
+const kafkaTimeout = 30 * time.Second
+
+// kafka publishes stories as JSON messages to a Kafka topic given by a
+// template, keyed by the ID of the story, so that a story always goes to
+// the same partition. It speaks only what a producer needs of the
+// protocol, Metadata v4 and Produce v3, which brokers support from Kafka
+// 1.0 on, without SASL authentication.
+type kafka struct {
+	brokers []*url.URL // to ask for the leader of the partition
+	topic   *template.Template
+}
+
+func newKafka(c *config) (sink, error) {
+	brokers := c.Kafka.Brokers
+	if brokers == "" {
+		brokers = os.Getenv("KAFKA_BROKERS")
+	}
+	if brokers == "" {
+		return nil, errors.New("no brokers: set kafka.brokers in the config file or $KAFKA_BROKERS")
+	}
+	k := new(kafka)
+	for _, b := range splitList(brokers) {
+		u, err := url.Parse(b)
+		if err != nil {
+			return nil, err
+		}
+		switch u.Scheme {
+		case "kafka", "tls":
+		default:
+			return nil, fmt.Errorf("invalid broker %q: want kafka://HOST:PORT or tls://HOST:PORT", b)
+		}
+		k.brokers = append(k.brokers, u)
+	}
+	topic := c.Kafka.Topic
+	if topic == "" {
+		topic = "news.{{.Search}}"
+	}
+	t, err := template.New("topic").Parse(topic)
+	if err != nil {
+		return nil, fmt.Errorf("topic: %v", err)
+	}
+	k.topic = t
+	return k, nil
+}
+
+func (k *kafka) save(it *item, tags []string) error {
+	topic, err := k.topicFor(it, tags)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	return k.publish(topic, []byte(strconv.Itoa(it.ID)), payload)
+}
+
+func (k *kafka) saveBatch(items []item, tags []string) error {
+	topic, err := k.topicFor(new(item), tags)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return k.publish(topic, nil, payload)
+}
+
+// kafkaTopic matches the names Kafka allows for topics.
+var kafkaTopic = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+// topicFor returns the topic to publish the story it, saved with tags, on.
+func (k *kafka) topicFor(it *item, tags []string) (string, error) {
+	topic, err := topicName(k.topic, it, tags)
+	if err != nil {
+		return "", err
+	}
+	if !kafkaTopic.MatchString(topic) {
+		return "", fmt.Errorf("invalid topic %q: want letters, digits, dots, dashes and underscores", topic)
+	}
+	return topic, nil
+}
+
+// publish publishes the message value, with key, if not nil, on topic,
+// asking each broker in turn for the leader of its partition until one
+// answers.
+func (k *kafka) publish(topic string, key, value []byte) error {
+	var errs []error
+	for _, b := range k.brokers {
+		err := k.publishVia(b, topic, key, value)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %v", b.Host, err))
+	}
+	return errors.Join(errs...)
+}
+
+func (k *kafka) publishVia(broker *url.URL, topic string, key, value []byte) error {
+	secure := broker.Scheme == "tls"
+	addr := hostPort(broker, "9092")
+	conn, err := kafkaDial(addr, broker.Hostname(), secure)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	leader, partition, err := conn.leader(topic, key)
+	if err != nil {
+		return err
+	}
+	if leader != addr {
+		host, _, _ := net.SplitHostPort(leader)
+		lconn, err := kafkaDial(leader, host, secure)
+		if err != nil {
+			return err
+		}
+		defer lconn.Close()
+		conn = lconn
+	}
+	return conn.produce(topic, partition, key, value)
+}
+
+// A kafkaConn is a connection to a broker.
+type kafkaConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func kafkaDial(addr, host string, secure bool) (*kafkaConn, error) {
+	dialer := &net.Dialer{Timeout: kafkaTimeout}
+	var conn net.Conn
+	var err error
+	if secure {
+		tc := tlsConfig.Clone()
+		tc.ServerName = host
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tc)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(kafkaTimeout))
+	return &kafkaConn{Conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// maxKafkaResponse bounds the size of the responses read from brokers.
+const maxKafkaResponse = 16 << 20
+
+// request sends the request apiKey, of the given version, with body, and
+// returns the body of the response.
+func (c *kafkaConn) request(apiKey, version int16, body []byte) (*kafkaReader, error) {
+	const correlationID = 1              // one request at a time
+	p := make([]byte, 4, 4+14+len(body)) // size, set below
+	p = binary.BigEndian.AppendUint16(p, uint16(apiKey))
+	p = binary.BigEndian.AppendUint16(p, uint16(version))
+	p = binary.BigEndian.AppendUint32(p, correlationID)
+	p = kafkaString(p, "news")
+	p = append(p, body...)
+	binary.BigEndian.PutUint32(p, uint32(len(p)-4))
+	if _, err := c.Write(p); err != nil {
+		return nil, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(c.r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < 4 || n > maxKafkaResponse {
+		return nil, fmt.Errorf("invalid response size %d", n)
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, err
+	}
+	if id := binary.BigEndian.Uint32(resp); id != correlationID {
+		return nil, fmt.Errorf("response to request %d, want %d", id, correlationID)
+	}
+	return &kafkaReader{b: resp[4:]}, nil
+}
+
+// leader returns the address of the leader of the partition of topic for
+// key, and the partition: the same for every message with the key, or, if
+// it is nil, one at random.
+func (c *kafkaConn) leader(topic string, key []byte) (addr string, partition int32, err error) {
+	var body []byte
+	body = binary.BigEndian.AppendUint32(body, 1) // topics
+	body = kafkaString(body, topic)
+	body = append(body, 1) // allow_auto_topic_creation
+	r, err := c.request(3, 4, body)
+	if err != nil {
+		return "", 0, fmt.Errorf("metadata: %v", err)
+	}
+	r.int32() // throttle_time_ms
+	brokers := make(map[int32]string)
+	for range r.count() {
+		id := r.int32()
+		host := r.string()
+		port := r.int32()
+		r.string() // rack
+		brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.string() // cluster_id
+	r.int32()  // controller_id
+	type partitionInfo struct {
+		index, leader int32
+		code          int16
+	}
+	var partitions []partitionInfo
+	var code int16
+	for range r.count() {
+		if c := r.int16(); c != 0 {
+			code = c
+		}
+		r.string() // name
+		r.int8()   // is_internal
+		for range r.count() {
+			var p partitionInfo
+			p.code = r.int16()
+			p.index = r.int32()
+			p.leader = r.int32()
+			for range r.count() { // replica_nodes
+				r.int32()
+			}
+			for range r.count() { // isr_nodes
+				r.int32()
+			}
+			partitions = append(partitions, p)
+		}
+	}
+	if r.err != nil {
+		return "", 0, fmt.Errorf("metadata: %v", r.err)
+	}
+	if code != 0 {
+		return "", 0, fmt.Errorf("metadata: %s", kafkaError(code))
+	}
+	if len(partitions) == 0 {
+		return "", 0, errors.New("metadata: no partitions")
+	}
+	slices.SortFunc(partitions, func(a, b partitionInfo) int { return int(a.index - b.index) })
+	p := partitions[rand.IntN(len(partitions))]
+	if key != nil {
+		p = partitions[crc32.ChecksumIEEE(key)%uint32(len(partitions))]
+	}
+	if p.code != 0 {
+		return "", 0, fmt.Errorf("metadata: partition %d: %s", p.index, kafkaError(p.code))
+	}
+	addr, ok := brokers[p.leader]
+	if !ok {
+		return "", 0, fmt.Errorf("metadata: partition %d has no leader", p.index)
+	}
+	return addr, p.index, nil
+}
+
+// produce publishes the message value, with key, if not nil, on the
+// partition of topic, and waits for all its replicas to have it.
+func (c *kafkaConn) produce(topic string, partition int32, key, value []byte) error {
+	batch := kafkaRecordBatch(key, value, time.Now())
+	var body []byte
+	body = binary.BigEndian.AppendUint16(body, 0xffff) // transactional_id, null
+	body = binary.BigEndian.AppendUint16(body, 0xffff) // acks, -1 for all replicas
+	body = binary.BigEndian.AppendUint32(body, uint32(kafkaTimeout/time.Millisecond))
+	body = binary.BigEndian.AppendUint32(body, 1) // topics
+	body = kafkaString(body, topic)
+	body = binary.BigEndian.AppendUint32(body, 1) // partitions
+	body = binary.BigEndian.AppendUint32(body, uint32(partition))
+	body = binary.BigEndian.AppendUint32(body, uint32(len(batch)))
+	body = append(body, batch...)
+	r, err := c.request(0, 3, body)
+	if err != nil {
+		return fmt.Errorf("produce: %v", err)
+	}
+	var code int16
+	for range r.count() {
+		r.string() // name
+		for range r.count() {
+			r.int32() // index
+			if c := r.int16(); c != 0 {
+				code = c
+			}
+			r.int64() // base_offset
+			r.int64() // log_append_time_ms
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("produce: %v", r.err)
+	}
+	if code != 0 {
+		return fmt.Errorf("produce: %s", kafkaError(code))
+	}
+	return nil
+}
+
+// kafkaRecordBatch returns a record batch, in the format of version 2,
+// holding the single record of value, with key, if not nil.
+func kafkaRecordBatch(key, value []byte, t time.Time) []byte {
+	var rec []byte
+	rec = append(rec, 0)              // attributes
+	rec = binary.AppendVarint(rec, 0) // timestamp_delta
+	rec = binary.AppendVarint(rec, 0) // offset_delta
+	if key == nil {
+		rec = binary.AppendVarint(rec, -1)
+	} else {
+		rec = binary.AppendVarint(rec, int64(len(key)))
+		rec = append(rec, key...)
+	}
+	rec = binary.AppendVarint(rec, int64(len(value)))
+	rec = append(rec, value...)
+	rec = binary.AppendVarint(rec, 0) // headers
+
+	// The part of the batch after the CRC, which covers it.
+	var b []byte
+	b = binary.BigEndian.AppendUint16(b, 0) // attributes
+	b = binary.BigEndian.AppendUint32(b, 0) // last_offset_delta
+	ms := uint64(t.UnixMilli())
+	b = binary.BigEndian.AppendUint64(b, ms)         // base_timestamp
+	b = binary.BigEndian.AppendUint64(b, ms)         // max_timestamp
+	b = binary.BigEndian.AppendUint64(b, 1<<64-1)    // producer_id, -1
+	b = binary.BigEndian.AppendUint16(b, 0xffff)     // producer_epoch, -1
+	b = binary.BigEndian.AppendUint32(b, 0xffffffff) // base_sequence, -1
+	b = binary.BigEndian.AppendUint32(b, 1)          // records
+	b = binary.AppendVarint(b, int64(len(rec)))
+	b = append(b, rec...)
+
+	var batch []byte
+	batch = binary.BigEndian.AppendUint64(batch, 0)                    // base_offset
+	batch = binary.BigEndian.AppendUint32(batch, uint32(4+1+4+len(b))) // batch_length
+	batch = binary.BigEndian.AppendUint32(batch, 0xffffffff)           // partition_leader_epoch, -1
+	batch = append(batch, 2)                                           // magic
+	batch = binary.BigEndian.AppendUint32(batch, crc32.Checksum(b, crc32.MakeTable(crc32.Castagnoli)))
+	return append(batch, b...)
+}
+
+// kafkaString appends s to p as a string of the protocol, prefixed with
+// its length.
+func kafkaString(p []byte, s string) []byte {
+	p = binary.BigEndian.AppendUint16(p, uint16(len(s)))
+	return append(p, s...)
+}
+
+// A kafkaReader reads the fields of a response. Once one cannot be read,
+// err is set and the rest read as zero.
+type kafkaReader struct {
+	b   []byte
+	err error
+}
+
+func (r *kafkaReader) next(n int) []byte {
+	if r.err != nil || n > len(r.b) {
+		if r.err == nil {
+			r.err = errors.New("short response")
+		}
+		return make([]byte, n)
+	}
+	p := r.b[:n]
+	r.b = r.b[n:]
+	return p
+}
+
+func (r *kafkaReader) int8() int8   { return int8(r.next(1)[0]) }
+func (r *kafkaReader) int16() int16 { return int16(binary.BigEndian.Uint16(r.next(2))) }
+func (r *kafkaReader) int32() int32 { return int32(binary.BigEndian.Uint32(r.next(4))) }
+func (r *kafkaReader) int64() int64 { return int64(binary.BigEndian.Uint64(r.next(8))) }
+
+// string reads a string, or a null one as empty.
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+// count reads the length of an array, or of a null one as zero.
+func (r *kafkaReader) count() int {
+	n := r.int32()
+	if n < 0 || int(n) > len(r.b) {
+		if n > 0 && r.err == nil {
+			r.err = errors.New("short response")
+		}
+		return 0
+	}
+	return int(n)
+}
+
+// kafkaErrors names the error codes a producer may get.
+var kafkaErrors = map[int16]string{
+	2:  "corrupt message",
+	3:  "unknown topic or partition",
+	5:  "leader not available",
+	6:  "not leader or follower",
+	7:  "request timed out",
+	10: "message too large",
+	17: "invalid topic",
+	19: "not enough replicas",
+	29: "topic authorization failed",
+	35: "unsupported version",
+}
+
+func kafkaError(code int16) string {
+	if s, ok := kafkaErrors[code]; ok {
+		return s
+	}
+	return fmt.Sprintf("error code %d", code)
+}
@@ -0,0 +1,46 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// A label names one of several patterns searched for at once, so that
+// each match can be tagged with the patterns it hit.
+type label struct {
+	name string
+	re   *pattern
+}
+
+// compileLabels compiles each of patterns into a label named after it,
+// and returns them along with a single pattern that matches any.
+func compileLabels(patterns []string) ([]label, *pattern, error) {
+	labels := make([]label, len(patterns))
+	res := make([]*pattern, len(patterns))
+	for i, p := range patterns {
+		re, err := compile(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		labels[i] = label{name: p, re: re}
+		res[i] = re
+	}
+	u, err := union(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	return labels, u, nil
+}
+
+// addLabels sets the Labels field of items to the names of the labels
+// whose patterns they match, in their title, text or page content.
+func addLabels(items []item, labels []label) {
+	for i := range items {
+		it := &items[i]
+		content := normalize(it.Content, *fold)
+		for _, l := range labels {
+			if it.matches(l.re) || content != "" && l.re.matchText(content) {
+				it.Labels = append(it.Labels, l.name)
+			}
+		}
+	}
+}
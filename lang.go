@@ -0,0 +1,130 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// scriptLanguages are the languages told apart by their script alone.
+var scriptLanguages = []struct {
+	lang  string
+	table *unicode.RangeTable
+}{
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"ko", unicode.Hangul},
+	{"zh", unicode.Han},
+	{"ru", unicode.Cyrillic},
+	{"el", unicode.Greek},
+	{"ar", unicode.Arabic},
+	{"he", unicode.Hebrew},
+	{"hi", unicode.Devanagari},
+	{"th", unicode.Thai},
+}
+
+// languageWords are frequent words of languages written in Latin script,
+// chosen to be rare in the other languages listed.
+var languageWords = map[string][]string{
+	"en": strings.Fields("the and of to is in that it for with on are was be this how why what your you from not can by an at has have will we do does my"),
+	"es": strings.Fields("el la los las de del que en y es por para una un con se su sus al lo como más pero qué cómo ya muy también"),
+	"fr": strings.Fields("le la les des du de et est une un en pour dans que qui sur pas au aux ce cette avec sont il elle nous vous comment pourquoi"),
+	"de": strings.Fields("der die das und ist nicht ein eine mit für auf den dem des zu im sich von wie warum ich wir sie es auch oder bei"),
+	"it": strings.Fields("il lo la gli le di del della che e è un una per con non sono come perché anche nel nella questo alla"),
+	"pt": strings.Fields("o a os as de do da dos das que e é um uma para com não em no na por como mais mas são isso também"),
+	"nl": strings.Fields("de het een en van is dat niet op te zijn met voor hoe waarom wat ik je we ze er maar ook bij"),
+}
+
+// languageLetters are letters that give away a language written in Latin
+// script.
+var languageLetters = map[rune]string{
+	'ñ': "es", '¿': "es", '¡': "es",
+	'ç': "fr", 'œ': "fr", 'è': "fr", 'ê': "fr", 'à': "fr",
+	'ß': "de", 'ä': "de", 'ö': "de", 'ü': "de",
+	'ã': "pt", 'õ': "pt",
+	'ì': "it", 'ò': "it",
+}
+
+// detectLanguage guesses the language s is written in and returns its
+// ISO 639-1 code, or "" if s does not say enough to tell. Scripts other
+// than Latin decide the language on their own; for Latin script, the
+// frequent words and distinctive letters of each language are counted.
+func detectLanguage(s string) string {
+	scripts := make(map[string]int)
+	letters := 0
+	score := make(map[string]int)
+	for _, r := range strings.ToLower(s) {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, sl := range scriptLanguages {
+			if unicode.Is(sl.table, r) {
+				scripts[sl.lang]++
+				break
+			}
+		}
+		if lang, ok := languageLetters[r]; ok {
+			score[lang]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Japanese mixes kana with Han characters, so any kana decides it.
+	if scripts["ja"] > 0 {
+		return "ja"
+	}
+	best, n := "", 0
+	for lang, c := range scripts {
+		if c > n || (c == n && lang < best) {
+			best, n = lang, c
+		}
+	}
+	if n*2 > letters {
+		return best
+	}
+
+	for _, w := range tokenize(s) {
+		for lang, words := range languageWords {
+			if slices.Contains(words, w) {
+				score[lang] += 2
+			}
+		}
+	}
+	best, n = "", 0
+	tie := false
+	for lang, c := range score {
+		switch {
+		case c > n:
+			best, n, tie = lang, c, false
+		case c == n:
+			tie = true
+		}
+	}
+	if n < 2 || tie {
+		return ""
+	}
+	return best
+}
+
+// filterLanguage returns the items written in one of langs. Items whose
+// language cannot be told are kept. The text of the linked page is taken
+// into account when it was fetched.
+func filterLanguage(items []item, langs []string) []item {
+	var kept []item
+	for _, it := range items {
+		text := it.PlainTitle()
+		if it.Content != "" {
+			text += "\n" + it.Content
+		}
+		if lang := detectLanguage(text); lang == "" || slices.Contains(langs, lang) {
+			kept = append(kept, it)
+		}
+	}
+	return kept
+}
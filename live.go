@@ -0,0 +1,134 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The web UI of the serve command is a page with a search box and
+// filters, whose results are kept up to date through /events.
+
+//go:embed ui
+var uiFiles embed.FS
+
+var uiTemplate = template.Must(template.New("index.html").Funcs(template.FuncMap{
+	"css": func() template.CSS { return reportCSS[*themeName] },
+}).ParseFS(uiFiles, "ui/index.html"))
+
+// addUIRoutes adds the web UI to mux.
+func addUIRoutes(mux *http.ServeMux) {
+	static, _ := fs.Sub(uiFiles, "ui")
+	mux.Handle("GET /ui/", http.StripPrefix("/ui/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /{$}", serveUI)
+	mux.HandleFunc("GET /events", serveEvents)
+	mux.HandleFunc("GET /ws", serveWebSocket)
+}
+
+func serveUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := uiTemplate.Execute(w, searches(r)); err != nil {
+		log.Print(err)
+	}
+}
+
+// A liveQuery is a search whose new matches are sent to a client as they
+// appear, as by the web UI. Its parameters are those of a watchlist
+// entry: the pattern q, the list, and the least points and comments.
+type liveQuery struct {
+	list  string
+	entry watchEntry
+	re    *pattern
+}
+
+func parseLiveQuery(r *http.Request) (*liveQuery, error) {
+	v := r.URL.Query()
+	q := &liveQuery{list: v.Get("list"), entry: watchEntry{Pattern: v.Get("q")}}
+	switch q.list {
+	case "":
+		q.list = "new"
+	case "new", "top", "best":
+	default:
+		return nil, fmt.Errorf("invalid list %q", q.list)
+	}
+	var err error
+	if q.re, err = compile(q.entry.Pattern); err != nil {
+		return nil, err
+	}
+	for name, n := range map[string]*int{"points": &q.entry.MinScore, "comments": &q.entry.MinComments} {
+		if s := v.Get(name); s != "" {
+			if *n, err = strconv.Atoi(s); err != nil || *n < 0 {
+				return nil, fmt.Errorf("invalid %s %q", name, s)
+			}
+		}
+	}
+	return q, nil
+}
+
+// watch sends the matches of q, and then the new ones every sharedTTL,
+// until ctx is done or send fails. Errors of a search are sent with
+// fail and do not stop it.
+func (q *liveQuery) watch(ctx context.Context, send func(item) error, fail func(error) error) error {
+	seen := make(map[int]bool)
+	for {
+		result, err := search(q.list, q.re)
+		if err != nil {
+			if err := fail(err); err != nil {
+				return err
+			}
+		} else {
+			for _, it := range unseen(seen, q.entry.filter(q.re, result.Items)) {
+				if err := send(it); err != nil {
+					return err
+				}
+			}
+		}
+		if !sleepUntil(ctx, time.Now().Add(sharedTTL)) {
+			return nil
+		}
+	}
+}
+
+// serveEvents sends the matches of the live query in the URL as server-
+// sent events: an item event with the JSON of each, and an error event
+// with the JSON string of the message when a search fails.
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	q, err := parseLiveQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+	event := func(name string, data []byte) error {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	q.watch(r.Context(), func(it item) error {
+		data, err := json.Marshal(it)
+		if err != nil {
+			return err
+		}
+		return event("item", data)
+	}, func(err error) error {
+		log.Printf("events: %v", err)
+		data, _ := json.Marshal(err.Error())
+		return event("error", data)
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A logFile is a log file that is rotated when it grows past maxSize
+// bytes or gets older than maxAge, whichever comes first; zero disables
+// either limit. Rotated files are renamed with the time of the rotation
+// appended, and only the newest keep of them are kept, or all if keep is
+// zero.
+type logFile struct {
+	name    string
+	maxSize int64
+	maxAge  time.Duration
+	keep    int
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	created time.Time
+}
+
+// openLogFile opens the log file name, appending to it if it exists.
+func openLogFile(name string, maxSize int64, maxAge time.Duration, keep int) (*logFile, error) {
+	l := &logFile{name: name, maxSize: maxSize, maxAge: maxAge, keep: keep}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *logFile) open() error {
+	f, err := os.OpenFile(l.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	// The modification time of a file that already has entries is the
+	// closest to its creation time that is portable, and errs on the side
+	// of rotating later.
+	l.f, l.size, l.created = f, fi.Size(), time.Now()
+	if fi.Size() > 0 {
+		l.created = fi.ModTime()
+	}
+	return nil
+}
+
+func (l *logFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.size > 0 && (l.maxSize > 0 && l.size+int64(len(p)) > l.maxSize ||
+		l.maxAge > 0 && time.Since(l.created) >= l.maxAge) {
+		if err := l.rotate(); err != nil {
+			// Keep logging to the current file rather than losing
+			// entries.
+			os.Stderr.WriteString("rotating log: " + err.Error() + "\n")
+		}
+	}
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file and starts a new one. If the new one
+// cannot be opened, the current one is kept, under its name.
+func (l *logFile) rotate() error {
+	rotated := l.name + "." + time.Now().Format("20060102-150405.000000")
+	if err := os.Rename(l.name, rotated); err != nil {
+		return err
+	}
+	old := l.f
+	if err := l.open(); err != nil {
+		os.Rename(rotated, l.name)
+		return err
+	}
+	old.Close()
+	if l.keep > 0 {
+		// The time stamps sort in the order the files were rotated.
+		old, _ := filepath.Glob(l.name + ".[0-9]*-[0-9]*")
+		slices.Sort(old)
+		for _, name := range old[:max(0, len(old)-l.keep)] {
+			os.Remove(name)
+		}
+	}
+	return nil
+}
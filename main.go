@@ -9,15 +9,18 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"html/template"
+	"io"
 	"log"
 	"net/http"
 	"os"
-	"regexp"
+	"slices"
 	"strconv"
+	"time"
 )
 
 type item struct {
@@ -26,114 +29,648 @@ type item struct {
 	Type        string // the type of item. One of "job", "story", "comment", "poll", or "pollopt".
 	By          string // the username of the item's author.
 	Time        int64  // creation date of the item, in Unix Time.
-	Text        string // the comment, story or pool text. HTML.
+	Text        string // the comment, story or pool text. HTML; see PlainText.
 	Dead        bool   // true if the item is dead.
 	Parent      int    // the comment's parent: either another comment or the relevant story.
 	Poll        int    // the pollopt's associated poll.
 	Kids        []int  // the ids of the item's comments, in ranked display order.
 	URL         string // the URL of the story
 	Score       int
-	Title       template.HTML // the title of the story, poll or job. HTML.
+	Title       string // the title of the story, poll or job. HTML; see PlainTitle.
 	Parts       []int
 	Descendants int // in the case of stories or polls, the total comment count.
+
+	// Set with -frontpage.
+	Rank          int  `json:",omitempty"` // position on the front page, or 0
+	RankEstimated bool `json:",omitempty"` // Rank is estimated from the score and age
+
+	Archive string `json:",omitempty"` // Wayback Machine snapshot of URL, set with -archive-links
+	Content string `json:"-"`          // text of the linked page, if it was what matched
+	Snippet string `json:",omitempty"` // the text of Content around the match
+	Summary string `json:",omitempty"` // output of -summarize-cmd
+
+	// Labels are the patterns the item matched, when searching for
+	// several at once.
+	Labels []string `json:",omitempty"`
+
+	// Author tells about the submitter, with -enrich-users.
+	Author *author `json:",omitempty"`
+
+	// Meta tells how the item was fetched, with -verbose-meta.
+	Meta *fetchMeta `json:",omitempty"`
+
+	// Extra holds the fields of the API the struct has none for; see
+	// UnmarshalJSON.
+	Extra map[string]json.RawMessage `json:"-"`
+
+	raw json.RawMessage // the item as the API returned it, if fetched
+}
+
+// hidden reports whether the item is left out of searches: dead, unless
+// -include-dead is set, deleted, unless -include-deleted is, or excluded
+// with -exclude-hidden or -unseen-only; see excludedStories.
+func (it *item) hidden() bool {
+	return it.Dead && !*withDead || it.Deleted && !*withDeleted || excludedStories[it.ID]
+}
+
+// Created returns the creation time of the item.
+func (it *item) Created() time.Time {
+	return time.Unix(it.Time, 0)
 }
 
 const basePath = "https://hacker-news.firebaseio.com/v0"
 
 var (
-	news = flag.Bool("new", true, "new stories")
-	top  = flag.Bool("top", false, "top stories")
-	best = flag.Bool("best", false, "best stories")
+	news      = flag.Bool("new", false, "new stories (the default)")
+	top       = flag.Bool("top", false, "top stories")
+	best      = flag.Bool("best", false, "best stories")
+	offset    = flag.Int("offset", 0, "skip the first `n` stories of the list")
+	limitIDs  = flag.Int("limit-ids", 0, "search at most `n` stories of the list, such as 30 for the front page; 0 means all")
+	titleOnly = flag.Bool("title-only", false, "match titles alone, fetching only the stories whose titles match, and caching titles between runs")
+	firstOnly = flag.Bool("first", false, "stop at the first story that matches, cancelling the other fetches")
+	quiet     = flag.Bool("q", false, "quiet; print nothing and exit with status 0 if any story matches")
+
+	withDead    = flag.Bool("include-dead", false, "also search the stories that are dead, such as those flagged by users or killed by moderators")
+	withDeleted = flag.Bool("include-deleted", false, "also search the stories that were deleted")
+	noHidden    = flag.Bool("exclude-hidden", false, "leave out the stories hidden on the Hacker News site by the account in the configuration file")
+	unseenOnly  = flag.Bool("unseen-only", false, "leave out the stories the account in the configuration file upvoted or commented on, on the Hacker News site")
+
+	configFile = flag.String("config", defaultConfig(), "configuration `file`")
+	searchName = flag.String("name", "", "run the saved search `name` from the configuration file")
+	dedupAll   = flag.Duration("dedup-window", 0, "do not save a story to a sink again within `duration`, even if it matches again")
+	saveTo     = flag.String("save-to", "", "comma-separated `sinks` to save the matches to: email, instapaper, kafka, mqtt, nats, pinboard, readwise or wallabag")
+
+	outSpecs      = newListFlag("out", "write the matches to `dest` instead of printing them, repeatable: FORMAT[:FILE], with html, text, json, jsonl, raw, tsv or rss, to standard output without FILE, or sqlite, parquet or bookmarks; webhook:URL; or a FILE whose extension tells the format. FILEs ending in .gz are compressed")
+	resumeFile    = flag.String("resume", "", "record the stories checked in `file`, and skip those recorded there, so that a long search that was interrupted resumes where it left off; the file is removed once the outputs are written")
+	appendOut     = flag.Bool("append", false, "merge the matches into the json and jsonl files of -out, replacing the stories already there by ID, instead of overwriting them; sqlite files always accumulate")
+	exportTo      = flag.String("export", "", "also write the matches to `dest`: sqlite:FILE, parquet:FILE or bookmarks:FILE, or a FILE whose extension tells the kind")
+	resolveLinks  = flag.Bool("resolve-urls", false, "follow shortened links to their target and strip tracking parameters from URLs")
+	maxResolve    = flag.Int("max-resolve", 50, "with -resolve-urls, follow at most `n` shortened links")
+	upvote        = flag.Bool("upvote", false, "upvote the matches on the Hacker News site with the account in the configuration file")
+	favorite      = flag.Bool("favorite", false, "favorite the matches on the Hacker News site with the account in the configuration file")
+	enrichUsers   = flag.Bool("enrich-users", false, "fetch the submitters of the matches, adding their karma and the age of their accounts to the output")
+	minKarma      = flag.Int("min-karma", 0, "keep only the matches submitted by users with at least `n` karma, fetching them as -enrich-users does; those whose submitters cannot be fetched are kept")
+	archiveLinks  = flag.Bool("archive-links", false, "link each story to a Wayback Machine snapshot of its URL, capturing one if needed")
+	frontPageRank = flag.Bool("frontpage", false, "show the rank of each story on the front page, estimated from its points and age if not there")
+
+	topCount     = flag.Int("n", 10, "number of entries in the rankings of the stats, trends and thread-stats commands, of stories in the widget, and of recent items of the user command")
+	interval     = flag.Duration("interval", 5*time.Minute, "how often the track and watch-item commands poll the stories; 0 polls once")
+	store        = flag.String("store", defaultStore(), "`file` where the track command records the stories")
+	listenAddr   = flag.String("listen", "localhost:8080", "`address` the serve command listens on")
+	cacheTTL     = flag.Duration("cache-ttl", 30*time.Second, "reuse the responses of the serve command for `duration`; 0 disables it")
+	corsOrigins  = flag.String("cors-origins", "", "comma-separated `origins`, such as https://example.com, whose pages may call the serve command; * allows any, but without credentials")
+	corsMethods  = flag.String("cors-methods", "GET,POST", "comma-separated `methods` allowed to those origins")
+	rateLimit    = flag.String("rate-limit", "", "`rate`, such as 60/m, of the requests the serve command allows each address or API key")
+	serverURL    = flag.String("server", "http://localhost:8080", "`URL` of the serve command the widget gets its stories from")
+	drainTimeout = flag.Duration("drain-timeout", 30*time.Second, "wait at most `duration` for requests and searches under way when the serve command shuts down")
+
+	logName    = flag.String("log-file", "", "write the log to `file` instead of standard error")
+	logMaxSize = flag.Int64("log-max-size", 10<<20, "with -log-file, rotate the log when it reaches `n` bytes; 0 means no limit")
+	logMaxAge  = flag.Duration("log-max-age", 0, "with -log-file, rotate the log when it is older than `duration`; 0 means no limit")
+	logKeep    = flag.Int("log-keep", 5, "with -log-file, keep the newest `n` rotated logs; 0 keeps all")
+
+	showTimings     = flag.Bool("timings", false, "print a summary of the requests made and their latency to standard error")
+	errorRateSpec   = flag.String("max-error-rate", "", "exit with status 2 if more than this `rate` of the stories, such as 5%, could not be fetched, after writing the matches of those that were")
+	errorSummary    = flag.String("error-summary", "text", "how to print the summary of the errors that did not stop the run, such as stories that could not be fetched, to standard error at its end: `format` is text, json or none")
+	debug           = flag.Bool("debug", false, "like -timings, and also report how connections were used")
+	maxIdleConns    = flag.Int("max-idle-conns", 100, "keep up to `n` idle connections per host for reuse")
+	idleTimeout     = flag.Duration("idle-timeout", 90*time.Second, "close idle connections after `duration`")
+	ip4             = flag.Bool("ip4", false, "connect over IPv4 only")
+	ip6             = flag.Bool("ip6", false, "connect over IPv6 only")
+	caFile          = flag.String("ca-file", "", "trust the certificate authorities in the PEM `file`, as well as the system ones, such as that of a TLS-intercepting proxy")
+	insecure        = flag.Bool("insecure-skip-verify", false, "do not verify TLS certificates; dangerous, prefer -ca-file")
+	maxRetries      = flag.Int("max-retries", 3, "retry failed requests up to `n` times")
+	retryMaxElapsed = flag.Duration("retry-max-elapsed", time.Minute, "wait at most `duration` in all for retries during a run; 0 means no limit")
+	http1           = flag.Bool("http1", false, "use HTTP/1.1 only, rather than HTTP/2 where servers support it")
+
+	textOut   = flag.Bool("text", false, "print a plain-text table instead of HTML")
+	columnsOf = flag.String("columns", "", "print a plain-text table of these comma-separated `fields`: NAME=EXPR, such as age=now-.Time or ratio=.Score/.Descendants, with EXPR a jq expression on the story, or just the name of one of its fields, such as score")
+	formatOf  = flag.String("format", "", "print each match as the `template` says: text in which each {FIELD}, as in -columns, is replaced by its value, such as '{.ID}\\t{now-.Time}\\t{title}'")
+	streamOut = flag.Bool("stream", false, "print each match as soon as it is found, as a line of tab-separated fields: ID, points, comments, author, time, title and URL")
+	rankOut   = flag.Bool("rank", false, "sort the matches by relevance, blending how well the pattern matches the title with points, recency and comments, rather than in the order of the story list")
+	jqSource  = flag.String("jq", "", "print what the jq `program`, such as '.Items[] | select(.Score > 100) | .URL', makes of the matches, as JSON: {\"Total\": N, \"Items\": [...]}; a subset of jq")
+	metaOut   = flag.Bool("verbose-meta", false, "add how each match was fetched to the JSON outputs, such as -out json, as Meta: {\"FetchMS\": milliseconds, \"CacheHit\": from the shared cache of the serve command, \"Retries\": N}")
+	rawOut    = flag.Bool("raw", false, "print each match as the JSON the API returned for it, byte for byte, one a line")
+	onlyMatch = flag.Bool("o", false, "print only the parts of stories that match, each on a line of tab-separated fields: ID, URL and the match")
+	ordered   = flag.Bool("ordered", false, "with -stream, print the matches in the order of the story list, while still fetching stories in parallel")
+	fold      = flag.Bool("fold", false, "ignore diacritics when matching, so that naive matches naïve")
+	fuzzy     = flag.Int("fuzzy", 0, "match titles containing the pattern, taken literally and ignoring case, within `n` edits, such as kubernetes for kuberentes")
+	stem      = flag.Bool("stem", false, "match the words of the pattern, taken literally, against those of titles reduced to their stems, so that deploy matches deploys, deployed and deployment")
+	glob      = flag.Bool("glob", false, "patterns are shell-style wildcards, such as 'Show HN: *', matching the whole title, text or part of the URL")
+
+	languages = flag.String("lang", "", "keep only stories in these comma-separated `languages` (ISO 639-1 codes such as en); stories whose language is unclear are kept")
+
+	withContent     = flag.Bool("fetch-content", false, "also match the pattern against the text of the pages stories link to")
+	contentWorkers  = flag.Int("content-workers", 8, "with -fetch-content, fetch at most `n` pages at a time")
+	contentMaxBytes = flag.Int64("content-max-bytes", 2<<20, "with -fetch-content, read at most `n` bytes of each page")
+	summarizeCmd    = flag.String("summarize-cmd", "", "pipe the article of each match to the shell `command` and show its output as a summary")
+	contextSize     = flag.Int("context", 80, "with -fetch-content, show up to `n` characters of the page around the match")
+
+	byAuthors      = flag.String("by", "", "with the comments and watch-item commands, print only the comments by these comma-separated `users`")
+	flatComments   = flag.Bool("flat", false, "with the comments command, print the comments in the order they were posted, rather than as indented threads")
+	wrapTitles     = flag.Bool("wrap", false, "with -text, wrap titles to fit the terminal width")
+	truncateTitles = flag.Bool("truncate", false, "with -text, truncate titles to fit the terminal width (the default on a terminal)")
+	colorMode      = flag.String("color", "auto", "with -text, whether to color the output: `when` is always, never or auto")
+	themeName      = flag.String("theme", "dark", "color `theme` of the -text and HTML output: dark or light")
+	pageSize       = flag.Int("page-size", 50, "split the HTML table into pages of `n` rows; 0 shows all rows at once")
+
+	timeFormat = flag.String("time-format", "relative", "how to print item times: `relative` (\"2h ago\") or absolute")
+	tz         = flag.String("tz", "Local", "time `zone` used to print item times, e.g. America/Argentina/Buenos_Aires")
 )
 
+// location is the time zone selected with -tz.
+var location *time.Location
+
 func main() {
 	log.SetFlags(0)
+	flag.Usage = usage
 	flag.Parse()
-	if len(flag.Args()) == 0 {
-		fmt.Fprintln(os.Stderr, "Usage: news [options] PATTERN")
-		flag.PrintDefaults()
+	if flag.NArg() == 0 && *searchName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := setup(); err != nil {
+		fatal(err)
+	}
+	defer reportTimings()
+	defer reportErrors()
+	switch flag.Arg(0) {
+	case "stats":
+		runStats(flag.Args()[1:])
+		return
+	case "trends":
+		runTrends(flag.Args()[1:])
+		return
+	case "track":
+		runTrack(flag.Args()[1:])
+		return
+	case "serve":
+		runServe()
+		return
+	case "publish":
+		runPublish(flag.Args()[1:])
+		return
+	case "widget":
+		runWidget(flag.Args()[1:])
+		return
+	case "user":
+		runUser(flag.Args()[1:])
+		return
+	case "thread-stats":
+		runThreadStats(flag.Args()[1:])
+		return
+	case "comments":
+		runComments(flag.Args()[1:])
+		return
+	case "watch-item":
+		runWatchItem(flag.Args()[1:])
+		return
+	case "favorites", "hidden":
+		runAccountList(flag.Arg(0), flag.Args()[1:])
+		return
+	}
+
+	s := savedSearch{Pattern: flag.Arg(0), List: listName()}
+	var labels []label
+	var re *pattern
+	var err error
+	if flag.NArg() > 1 {
+		if labels, re, err = compileLabels(flag.Args()); err != nil {
+			fatal(err)
+		}
+	}
+	if *searchName != "" {
+		if s, err = cfg().search(*searchName); err != nil {
+			fatal(err)
+		}
+		re = nil
+	}
+	s.SaveTo = append(s.SaveTo, splitList(*saveTo)...)
+	if re == nil {
+		if re, err = compile(s.Pattern); err != nil {
+			fatal(err)
+		}
+	}
+	langs := splitList(*languages)
+	var emit func(*item)
+	if *streamOut && !*quiet {
+		emit = func(it *item) {
+			if len(langs) == 0 || len(filterLanguage([]item{*it}, langs)) > 0 {
+				switch {
+				case *onlyMatch:
+					streamMatches(os.Stdout, it, re)
+				case *rawOut:
+					writeRawItem(os.Stdout, it)
+				case lineFormat != nil:
+					writeFormatted(os.Stdout, it)
+				default:
+					streamItem(os.Stdout, it)
+				}
+			}
+		}
+	}
+	if *noHidden || *unseenOnly {
+		if err := loadExcludedStories(); err != nil {
+			fatal(err)
+		}
+	}
+	if *resumeFile != "" {
+		if resume, err = openProgress(*resumeFile, s.List, re); err != nil {
+			fatal(err)
+		}
+	}
+	result, err := searchFunc(s.List, re, emit)
+	if err != nil {
+		fatal(err)
+	}
+	addLabels(result.Items, labels)
+	if len(langs) > 0 {
+		result.Items = filterLanguage(result.Items, langs)
+		result.Total = len(result.Items)
+	}
+	if *rankOut {
+		rankItems(result.Items, re)
+	}
+	if *frontPageRank && !*quiet {
+		fp, err := getFrontPage(true)
+		if err != nil {
+			fatal(err)
+		}
+		for i := range result.Items {
+			it := &result.Items[i]
+			it.Rank, it.RankEstimated = fp.rank(it)
+		}
+	}
+	if *enrichUsers || *minKarma > 0 {
+		addAuthors(result)
+	}
+	if *minKarma > 0 {
+		result.Items = filterKarma(result.Items, *minKarma)
+		result.Total = len(result.Items)
+	}
+	if *resolveLinks {
+		resolveURLs(result, *maxResolve)
+	}
+	if *archiveLinks {
+		addArchiveLinks(result)
+	}
+	if *summarizeCmd != "" {
+		addSummaries(result, *summarizeCmd)
+	}
+	if len(outputs) > 0 {
+		if err := writeOutputs(outputs, s, result); err != nil {
+			fatal(err)
+		}
+	} else if *onlyMatch && !*quiet && !*streamOut {
+		for i := range result.Items {
+			streamMatches(os.Stdout, &result.Items[i], re)
+		}
+	} else if jqProgram != nil && !*quiet {
+		if err := writeJQ(os.Stdout, jqProgram, result); err != nil {
+			fatal(err)
+		}
+	} else if lineFormat != nil && !*quiet && !*streamOut {
+		for i := range result.Items {
+			if err := writeFormatted(os.Stdout, &result.Items[i]); err != nil {
+				fatal(err)
+			}
+		}
+	} else if *rawOut && !*quiet && !*streamOut {
+		if err := writeRaw(os.Stdout, result); err != nil {
+			fatal(err)
+		}
+	} else if !*quiet && !*streamOut {
+		print := printHTML
+		switch {
+		case columns != nil:
+			print = printColumns
+		case *textOut:
+			print = printText
+		}
+		if err := print(os.Stdout, result); err != nil {
+			fatal(err)
+		}
+	}
+	if *exportTo != "" {
+		if err := export(*exportTo, s.List, result); err != nil {
+			fatal(err)
+		}
+	}
+	if len(s.SaveTo) > 0 {
+		if err := saveMatches(s.SaveTo, s.Name, result); err != nil {
+			fatal(err)
+		}
+	}
+	if err := actOnMatches(result); err != nil {
+		fatal(err)
+	}
+	if resume != nil {
+		if err := resume.finish(); err != nil {
+			fatal(err)
+		}
+	}
+	checkErrorRate()
+	if result.Total == 0 {
+		reportErrors()
+		reportTimings()
 		os.Exit(1)
 	}
-	pattern := flag.Arg(0)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: news [options] PATTERN...")
+	fmt.Fprintln(os.Stderr, "       news [options] -name SEARCH")
+	fmt.Fprintln(os.Stderr, "       news [options] stats [PATTERN]")
+	fmt.Fprintln(os.Stderr, "       news [options] trends [PATTERN]")
+	fmt.Fprintln(os.Stderr, "       news [options] track ID...")
+	fmt.Fprintln(os.Stderr, "       news [options] comments [ID] [PATTERN]")
+	fmt.Fprintln(os.Stderr, "       news [options] thread-stats ID")
+	fmt.Fprintln(os.Stderr, "       news [options] watch-item ID [PATTERN]")
+	fmt.Fprintln(os.Stderr, "       news [options] serve")
+	fmt.Fprintln(os.Stderr, "       news [options] publish DIR [SEARCH...]")
+	fmt.Fprintln(os.Stderr, "       news [options] widget PATTERN")
+	fmt.Fprintln(os.Stderr, "       news [options] user NAME")
+	fmt.Fprintln(os.Stderr, "       news [options] favorites [NAME]")
+	fmt.Fprintln(os.Stderr, "       news [options] hidden")
+	flag.PrintDefaults()
+}
+
+// setup checks the flags shared by all commands and sets up the state
+// that depends on them.
+func setup() error {
+	if *errorRateSpec != "" {
+		r, err := parseErrorRate(*errorRateSpec)
+		if err != nil {
+			return fmt.Errorf("-max-error-rate: %v", err)
+		}
+		maxErrorRate = r
+	}
+	switch *errorSummary {
+	case "text", "json", "none":
+	default:
+		return fmt.Errorf("invalid -error-summary %q: want text, json or none", *errorSummary)
+	}
+	switch *timeFormat {
+	case "relative", "absolute":
+	default:
+		return fmt.Errorf("invalid -time-format %q: want relative or absolute", *timeFormat)
+	}
+	switch *colorMode {
+	case "always", "never", "auto":
+	default:
+		return fmt.Errorf("invalid -color %q: want always, never or auto", *colorMode)
+	}
+	if *ip4 && *ip6 {
+		return fmt.Errorf("-ip4 and -ip6 are mutually exclusive")
+	}
+	if *maxRetries < 0 || *retryMaxElapsed < 0 {
+		return fmt.Errorf("-max-retries and -retry-max-elapsed must not be negative")
+	}
+	if *maxIdleConns < 0 || *idleTimeout < 0 {
+		return fmt.Errorf("-max-idle-conns and -idle-timeout must not be negative")
+	}
+	if *rateLimit != "" {
+		if _, err := parseErrorRate(*rateLimit); err != nil {
+			return fmt.Errorf("-rate-limit: %v", err)
+		}
+	}
+	if *drainTimeout < 0 || *cacheTTL < 0 {
+		return fmt.Errorf("-drain-timeout and -cache-ttl must not be negative")
+	}
+	outputs = nil
+	for _, spec := range *outSpecs {
+		o, err := parseOutput(spec)
+		if err != nil {
+			return err
+		}
+		if *appendOut && !o.appendable() {
+			return fmt.Errorf("-append: cannot merge into %s files, only json, jsonl and sqlite", o.format)
+		}
+		outputs = append(outputs, o)
+	}
+	if *minKarma > 0 && *streamOut {
+		return fmt.Errorf("-min-karma and -stream are mutually exclusive")
+	}
+	if *rankOut && *streamOut {
+		return fmt.Errorf("-rank and -stream are mutually exclusive")
+	}
+	if *onlyMatch && *rawOut {
+		return fmt.Errorf("-o and -raw are mutually exclusive")
+	}
+	var err error
+	if columns, err = parseColumns(*columnsOf); err != nil {
+		return err
+	}
+	if columns != nil && (*formatOf != "" || *streamOut) {
+		return fmt.Errorf("-columns cannot be used with -format or -stream")
+	}
+	lineFormat = nil
+	if *formatOf != "" {
+		if *onlyMatch || *rawOut || *jqSource != "" {
+			return fmt.Errorf("-format cannot be used with -o, -raw or -jq")
+		}
+		if lineFormat, err = parseFormat(*formatOf); err != nil {
+			return err
+		}
+	}
+	jqProgram = nil
+	if *jqSource != "" {
+		if *onlyMatch || *rawOut || *streamOut {
+			return fmt.Errorf("-jq cannot be used with -o, -raw or -stream")
+		}
+		if jqProgram, err = compileJQ(*jqSource); err != nil {
+			return err
+		}
+	}
+	if *onlyMatch && (*fuzzy > 0 || *stem) {
+		return fmt.Errorf("-o needs regular expressions, not -fuzzy or -stem")
+	}
+	if *fuzzy < 0 {
+		return fmt.Errorf("-fuzzy must not be negative")
+	}
+	if *fuzzy > 0 && *glob || *fuzzy > 0 && *stem || *glob && *stem {
+		return fmt.Errorf("-fuzzy, -glob and -stem are mutually exclusive")
+	}
+	if *offset < 0 || *limitIDs < 0 {
+		return fmt.Errorf("-offset and -limit-ids must not be negative")
+	}
+	if _, ok := themes[*themeName]; !ok {
+		return fmt.Errorf("invalid -theme %q: want dark or light", *themeName)
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("invalid -tz: %v", err)
+	}
+	location = loc
+	if *logName != "" {
+		f, err := openLogFile(*logName, *logMaxSize, *logMaxAge, *logKeep)
+		if err != nil {
+			return err
+		}
+		log.SetOutput(f)
+		log.SetFlags(log.LstdFlags)
+	}
+	explicit := false
+	flag.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "config" })
+	c, err := loadConfig(*configFile, explicit)
+	if err != nil {
+		return err
+	}
+	current.Store(c)
+	if err := setupTransport(); err != nil {
+		return err
+	}
+	return nil
+}
 
-	var which string
+// listName returns the name of the story list selected by the flags.
+func listName() string {
 	switch {
 	case *news:
-		which = "new"
+		return "new"
 	case *top:
-		which = "top"
+		return "top"
 	case *best:
-		which = "best"
+		return "best"
 	}
-	stories, err := getStories(which)
+	return "new"
+}
+
+// search fetches the stories of the given list and returns those that
+// match re. A nil re matches every story. With -first, it returns the
+// first story to match and cancels the fetches still under way.
+func search(list string, re *pattern) (*searchResult, error) {
+	return searchFunc(list, re, nil)
+}
+
+// searchFunc is like search, but also calls emit, if not nil, with each
+// match as soon as it is found, or, with -ordered, as soon as the stories
+// before it in the list have been fetched. Matches found in the pages the
+// stories link to, with -fetch-content, come last. Dead and deleted
+// stories are skipped; see item.hidden. Stories that cannot be
+// fetched are skipped, and their errors noted for -error-summary.
+func searchFunc(list string, re *pattern, emit func(*item)) (*searchResult, error) {
+	stories, err := getStories(list)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	stories = sliceStories(stories, *offset, *limitIDs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	matched := re == nil
+	if *titleOnly && re != nil && re.titleOnly() {
+		if stories, err = matchTitles(ctx, stories, re); err != nil {
+			return nil, err
+		}
+		matched = true
+	}
+	var items, rest []item
+	if resume != nil {
+		// Pick up the matches of the interrupted run, and skip the
+		// stories it checked.
+		items = append(items, resume.items...)
+		if *firstOnly {
+			items = items[:min(len(items), 1)]
+		}
+		for i := range items {
+			if emit != nil {
+				emit(&items[i])
+			}
+		}
+		if *firstOnly && len(items) > 0 {
+			return &searchResult{Total: 1, Items: items, re: re}, nil
+		}
+		stories = slices.DeleteFunc(stories, func(id int) bool { return resume.done[id] })
+	}
+	noteFetches(len(stories))
 	c := make(chan fetchResult, len(stories))
 	for _, id := range stories {
-		url := basePath + "/item/" + strconv.Itoa(id) + ".json"
-		go fetch(url, c)
+		go fetch(ctx, id, c)
 	}
-
-	search := func(pattern string) (*searchResult, error) {
-		var items []item
-		for range stories {
-			r := <-c
-			if r.err != nil {
-				return nil, r.err
+	// Order matters not when stopping at the first match.
+	order := newEmitter(stories, emit, *ordered && !*firstOnly)
+	for range stories {
+		r := <-c
+		if r.err != nil {
+			// Left unrecorded, to be fetched again on resuming.
+			noteError("item", r.ID, r.err)
+			order.add(&r.item, false)
+			continue
+		}
+		if r.item.ID == 0 {
+			// The API has no such item, which it answers with null.
+			order.add(&item{ID: r.id}, false)
+			continue
+		}
+		visible := !r.item.hidden()
+		if visible && (matched || r.item.matches(re)) {
+			items = append(items, r.item)
+			if err := resume.add(&r.item, true); err != nil {
+				return nil, err
 			}
-			matched, _ := regexp.MatchString(pattern, string(r.item.Title))
-			if matched {
-				items = append(items, r.item)
+			order.add(&r.item, true)
+			if *firstOnly {
+				return &searchResult{Total: 1, Items: items, re: re}, nil
+			}
+		} else {
+			order.add(&r.item, false)
+			if visible && *withContent && re.Regexp != nil {
+				// Left unrecorded, since the page may match yet.
+				rest = append(rest, r.item)
+			} else if err := resume.add(&r.item, false); err != nil {
+				return nil, err
 			}
 		}
-		return &searchResult{Total: len(items), Items: items}, nil
 	}
-	result, err := search(pattern)
-	if err != nil {
-		log.Fatal(err)
+	if len(rest) > 0 {
+		found := matchContent(rest, re)
+		if *firstOnly && len(found) > 1 {
+			found = found[:1]
+		}
+		for i := range found {
+			if emit != nil {
+				emit(&found[i])
+			}
+		}
+		items = append(items, found...)
 	}
-	if err := print(result); err != nil {
-		log.Fatal(err)
+	return &searchResult{Total: len(items), Items: items, re: re}, nil
+}
+
+// sliceStories returns the part of the story list ids that starts at
+// offset and has up to limit stories, or all the rest if limit is 0.
+func sliceStories(ids []int, offset, limit int) []int {
+	ids = ids[min(max(offset, 0), len(ids)):]
+	if limit > 0 {
+		ids = ids[:min(limit, len(ids))]
 	}
+	return ids
+}
+
+// fatal prints err and exits with status 2, so that scripts can tell
+// errors apart from a search that found nothing (status 1).
+func fatal(err error) {
+	log.Print(err)
+	os.Exit(2)
 }
 
 type searchResult struct {
 	Total int
 	Items []item
+
+	re *pattern // the pattern the items matched
 }
 
-func print(r *searchResult) error {
-	const templ = `
-<h1>{{.Total}} Hacker News stories</h1>
-<table style='border-spacing: 5px'>
-<tr style='text-align: left'>
-	<th>#</th>
-	<th>points</th>
-	<th>comments</th>
-	<th>author</th>
-	<th>title</th>
-</tr>
-{{range .Items}}
-<tr>
-	<td>{{.ID}}</td>
-	<td>{{.Score}}</td>
-	<td>{{.Descendants}}</td>
-	<td>{{.By}}</td>
-	<td><a href='{{.URL}}'>{{.Title}}</a></td>
-</tr>
-{{end}}
-</table>
-`
-	// TODO: add column "time".
-	t := template.Must(template.New("").Parse(templ))
-	if err := t.Execute(os.Stdout, r); err != nil {
-		return err
+// getStories fetches the IDs of the stories in the list which: new, top
+// or best.
+func getStories(which string) ([]int, error) {
+	if shared != nil {
+		return shared.storyList(which, fetchStories)
 	}
-	return nil
+	return fetchStories(which)
 }
 
-func getStories(which string) ([]int, error) {
+func fetchStories(which string) ([]int, error) {
 	url := "https://hacker-news.firebaseio.com/v0/" + which + "stories.json"
 	var stories []int
 	resp, err := http.Get(url)
@@ -148,21 +685,73 @@ func getStories(which string) ([]int, error) {
 	return stories, nil
 }
 
+// A fetchResult is an item fetched, or, if err is set, the ID of one that
+// could not be.
 type fetchResult struct {
 	item
+	id  int // the ID asked for, which null items lack
 	err error
 }
 
-func fetch(url string, c chan<- fetchResult) {
-	resp, err := http.Get(url)
+func fetch(ctx context.Context, id int, c chan<- fetchResult) {
+	it, err := getItemContext(ctx, id)
 	if err != nil {
-		c <- fetchResult{err: fmt.Errorf("fetch: %v", err)}
+		c <- fetchResult{item: item{ID: id}, id: id, err: err}
+		return
+	}
+	c <- fetchResult{item: *it, id: id}
+}
+
+// getItem fetches the item with the given id.
+func getItem(id int) (*item, error) {
+	return getItemContext(context.Background(), id)
+}
+
+// getItemContext is like getItem, but gives up when ctx is done.
+// With -verbose-meta, it records how in the Meta of the item.
+func getItemContext(ctx context.Context, id int) (*item, error) {
+	var meta *fetchMeta
+	if *metaOut {
+		meta = &fetchMeta{CacheHit: shared != nil}
+		ctx = context.WithValue(ctx, fetchMetaKey{}, meta)
+	}
+	start := time.Now()
+	var it *item
+	var err error
+	if shared != nil {
+		it, err = shared.item(id, func(id int) (*item, error) {
+			meta.fetched()
+			return fetchItem(ctx, id)
+		})
+	} else {
+		it, err = fetchItem(ctx, id)
+	}
+	if err == nil && meta != nil {
+		meta.FetchMS = float64(time.Since(start).Microseconds()) / 1000
+		it.Meta = meta
+	}
+	return it, err
+}
+
+func fetchItem(ctx context.Context, id int) (*item, error) {
+	url := basePath + "/item/" + strconv.Itoa(id) + ".json"
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
 	}
 	defer resp.Body.Close()
-	var item item
-	err = json.NewDecoder(resp.Body).Decode(&item)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c <- fetchResult{err: fmt.Errorf("fetch: %v", err)}
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	var it item
+	if err := json.Unmarshal(body, &it); err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
 	}
-	c <- fetchResult{item: item}
+	it.raw = bytes.TrimSpace(body)
+	return &it, nil
 }
@@ -0,0 +1,225 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// mqttTimeout bounds the time to connect to the broker and publish.
+const mqttTimeout = 30 * time.Second
+
+// mqtt publishes stories as JSON messages to an MQTT broker, with QoS 1,
+// on a topic given by a template.
+type mqtt struct {
+	broker   *url.URL
+	topic    *template.Template
+	username string
+	password string
+	retain   bool
+}
+
+func newMQTT(c *config) (sink, error) {
+	broker := c.MQTT.Broker
+	if broker == "" {
+		broker = os.Getenv("MQTT_BROKER")
+	}
+	if broker == "" {
+		return nil, errors.New("no broker: set mqtt.broker in the config file or $MQTT_BROKER")
+	}
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts":
+	default:
+		return nil, fmt.Errorf("invalid broker %q: want tcp://HOST:PORT or ssl://HOST:PORT", broker)
+	}
+	topic := c.MQTT.Topic
+	if topic == "" {
+		topic = "news/{{.Search}}"
+	}
+	t, err := template.New("topic").Parse(topic)
+	if err != nil {
+		return nil, fmt.Errorf("topic: %v", err)
+	}
+	m := &mqtt{broker: u, topic: t, retain: c.MQTT.Retain}
+	if u.User != nil {
+		m.username = u.User.Username()
+		m.password, _ = u.User.Password()
+	}
+	return m, nil
+}
+
+func (m *mqtt) save(it *item, tags []string) error {
+	topic, err := topicName(m.topic, it, tags)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	return m.publish(topic, payload)
+}
+
+func (m *mqtt) saveBatch(items []item, tags []string) error {
+	topic, err := topicName(m.topic, new(item), tags)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return m.publish(topic, payload)
+}
+
+// publish connects to the broker, publishes payload on topic and
+// waits for the broker to acknowledge it before disconnecting.
+func (m *mqtt) publish(topic string, payload []byte) error {
+	secure := m.broker.Scheme != "tcp" && m.broker.Scheme != "mqtt"
+	host := hostPort(m.broker, "1883")
+	if secure {
+		host = hostPort(m.broker, "8883")
+	}
+	dialer := &net.Dialer{Timeout: mqttTimeout}
+	var conn net.Conn
+	var err error
+	if secure {
+		tc := tlsConfig.Clone()
+		tc.ServerName = m.broker.Hostname()
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, tc)
+	} else {
+		conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(mqttTimeout))
+	r := bufio.NewReader(conn)
+
+	// CONNECT, with a clean session.
+	var p []byte
+	p = mqttString(p, "MQTT")
+	flags := byte(0x02)
+	if m.username != "" {
+		flags |= 0x80
+	}
+	if m.password != "" {
+		flags |= 0x40
+	}
+	p = append(p, 4, flags, 0, 60) // protocol level 3.1.1, keep alive 60s
+	// Brokers drop the session of a client when another connects with its
+	// ID, so each connection, even of the same process, has its own.
+	p = mqttString(p, "news-"+strconv.Itoa(os.Getpid())+"-"+rand.Text()[:8])
+	if m.username != "" {
+		p = mqttString(p, m.username)
+	}
+	if m.password != "" {
+		p = mqttString(p, m.password)
+	}
+	if err := mqttWrite(conn, 0x10, p); err != nil {
+		return err
+	}
+	typ, body, err := mqttRead(r)
+	if err != nil {
+		return err
+	}
+	if typ != 0x20 || len(body) != 2 {
+		return fmt.Errorf("unexpected packet %#x, want CONNACK", typ)
+	}
+	if body[1] != 0 {
+		return fmt.Errorf("connection refused (code %d)", body[1])
+	}
+
+	// PUBLISH, QoS 1, packet identifier 1.
+	header := byte(0x32)
+	if m.retain {
+		header |= 0x01
+	}
+	p = mqttString(nil, topic)
+	p = append(p, 0, 1)
+	p = append(p, payload...)
+	if err := mqttWrite(conn, header, p); err != nil {
+		return err
+	}
+	typ, body, err = mqttRead(r)
+	if err != nil {
+		return err
+	}
+	if typ != 0x40 || len(body) != 2 || body[1] != 1 {
+		return fmt.Errorf("unexpected packet %#x, want PUBACK", typ)
+	}
+	return mqttWrite(conn, 0xe0, nil) // DISCONNECT
+}
+
+// mqttString appends s to p as a length-prefixed UTF-8 string.
+func mqttString(p []byte, s string) []byte {
+	p = binary.BigEndian.AppendUint16(p, uint16(len(s)))
+	return append(p, s...)
+}
+
+// mqttWrite writes a control packet with the given fixed header byte.
+func mqttWrite(w io.Writer, header byte, body []byte) error {
+	p := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		p = append(p, b)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(p, body...))
+	return err
+}
+
+// mqttRead reads a control packet, returning its type (the high bits
+// of the fixed header) and its body.
+func mqttRead(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, shift := 0, 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		if shift += 7; shift > 21 {
+			return 0, nil, errors.New("malformed packet length")
+		}
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header & 0xf0, body, nil
+}
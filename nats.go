@@ -0,0 +1,174 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// natsTimeout bounds the time to connect to the server and publish.
+const natsTimeout = 30 * time.Second
+
+// nats publishes stories as JSON messages to a NATS server, on a subject
+// given by a template.
+type nats struct {
+	server  *url.URL
+	subject *template.Template
+	token   string
+}
+
+func newNATS(c *config) (sink, error) {
+	server := c.NATS.URL
+	if server == "" {
+		server = os.Getenv("NATS_URL")
+	}
+	if server == "" {
+		return nil, errors.New("no server: set nats.url in the config file or $NATS_URL")
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "nats", "tls":
+	default:
+		return nil, fmt.Errorf("invalid server %q: want nats://HOST:PORT or tls://HOST:PORT", server)
+	}
+	subject := c.NATS.Subject
+	if subject == "" {
+		subject = "news.{{.Search}}"
+	}
+	t, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("subject: %v", err)
+	}
+	token := c.NATS.Token
+	if token == "" {
+		token = os.Getenv("NATS_TOKEN")
+	}
+	return &nats{server: u, subject: t, token: token}, nil
+}
+
+func (n *nats) save(it *item, tags []string) error {
+	subject, err := n.subjectFor(it, tags)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	return n.publish(subject, payload)
+}
+
+func (n *nats) saveBatch(items []item, tags []string) error {
+	subject, err := n.subjectFor(new(item), tags)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return n.publish(subject, payload)
+}
+
+// subjectFor returns the subject to publish the story it, saved with tags,
+// on. Subjects cannot be empty or have whitespace, which would end them in
+// the PUB command.
+func (n *nats) subjectFor(it *item, tags []string) (string, error) {
+	subject, err := topicName(n.subject, it, tags)
+	if err != nil {
+		return "", err
+	}
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return "", fmt.Errorf("invalid subject %q", subject)
+	}
+	return subject, nil
+}
+
+// publish connects to the server and publishes payload on subject. The
+// connection is in verbose mode, so that the server acknowledges every
+// command or reports why it failed.
+func (n *nats) publish(subject string, payload []byte) error {
+	conn, err := net.DialTimeout("tcp", hostPort(n.server, "4222"), natsTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(natsTimeout))
+	r := bufio.NewReader(conn)
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	if rest, ok := strings.CutPrefix(line, "INFO "); !ok {
+		return fmt.Errorf("unexpected greeting %q", strings.TrimSpace(line))
+	} else if err := json.Unmarshal([]byte(rest), &info); err != nil {
+		return fmt.Errorf("INFO: %v", err)
+	}
+	if info.TLSRequired || n.server.Scheme == "tls" {
+		config := tlsConfig.Clone()
+		config.ServerName = n.server.Hostname()
+		tc := tls.Client(conn, config)
+		if err := tc.Handshake(); err != nil {
+			return err
+		}
+		conn = tc
+		r = bufio.NewReader(conn)
+	}
+
+	opts := map[string]any{
+		"verbose":  true,
+		"pedantic": false,
+		"name":     "news",
+		"lang":     "go",
+		"version":  "1",
+		"protocol": 0,
+	}
+	if n.token != "" {
+		opts["auth_token"] = n.token
+	}
+	if u := n.server.User; u != nil {
+		opts["user"] = u.Username()
+		opts["pass"], _ = u.Password()
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("CONNECT %s\r\nPUB %s %d\r\n%s\r\n", connect, subject, len(payload), payload)
+	if _, err := conn.Write([]byte(cmd)); err != nil {
+		return err
+	}
+	for range 2 { // one +OK for CONNECT and one for PUB
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		switch line = strings.TrimSpace(line); {
+		case line == "+OK":
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.Trim(strings.TrimSpace(line[len("-ERR"):]), "'"))
+		default:
+			return fmt.Errorf("unexpected reply %q", line)
+		}
+	}
+	return nil
+}
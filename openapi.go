@@ -0,0 +1,204 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// obj is a JSON object of the OpenAPI document.
+type obj = map[string]any
+
+// serveOpenAPI serves an OpenAPI 3 document describing the API of the
+// serve command, for generating clients and for API explorers. The
+// schemas of the JSON responses are generated from the Go types, so that
+// they do not fall behind.
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(openAPI(baseURL(r)))
+}
+
+func openAPI(server string) obj {
+	rss := obj{"200": obj{
+		"description": "An RSS 2.0 feed.",
+		"content":     obj{"application/rss+xml": obj{"schema": obj{"type": "string"}}},
+	}}
+	feedParams := []any{
+		param("q", "query", "Only the stories with this text in their title or URL.", obj{"type": "string"}),
+		param("points", "query", "Only the stories with at least this many points.", obj{"type": "integer", "minimum": 0}),
+		param("comments", "query", "Only the stories with at least this many comments.", obj{"type": "integer", "minimum": 0}),
+		param("count", "query", "The number of entries.", obj{"type": "integer", "minimum": 0, "maximum": hnrssMaxCount, "default": hnrssCount}),
+	}
+	liveParams := []any{
+		param("q", "query", "A regular expression the stories must match.", obj{"type": "string"}),
+		param("list", "query", "The list of stories to search.", obj{"type": "string", "enum": []string{"new", "top", "best"}, "default": "new"}),
+		param("points", "query", "Only the stories with at least this many points.", obj{"type": "integer", "minimum": 0}),
+		param("comments", "query", "Only the stories with at least this many comments.", obj{"type": "integer", "minimum": 0}),
+	}
+	hnrss := func(summary string) obj {
+		return obj{"get": obj{"summary": summary, "parameters": feedParams, "responses": rss}}
+	}
+	paths := obj{
+		"/feed/{name}": obj{"get": obj{
+			"summary":    "The matches of a saved search, as a feed.",
+			"parameters": []any{param("name", "path", "The name of the saved search.", obj{"type": "string"})},
+			"responses":  withStatus(rss, "404", "There is no saved search with that name."),
+		}},
+		"/searches.opml": obj{"get": obj{
+			"summary": "The feeds of all the saved searches, as an OPML list.",
+			"responses": obj{"200": obj{
+				"description": "An OPML 2.0 document.",
+				"content":     obj{"text/x-opml": obj{"schema": obj{"type": "string"}}},
+			}},
+		}},
+		"/trigger": obj{"post": obj{
+			"summary": "Run a search, saving the matches to sinks if asked to.",
+			"requestBody": obj{"required": true, "content": obj{"application/json": obj{"schema": obj{
+				"type": "object",
+				"properties": obj{
+					"name":    obj{"type": "string", "description": "The name of a saved search."},
+					"pattern": obj{"type": "string", "description": "A regular expression, if there is no name."},
+					"list":    obj{"type": "string", "enum": []string{"new", "top", "best"}, "default": "new"},
+					"save_to": obj{"type": "array", "items": obj{"type": "string"}, "description": "The names of sinks for the matches."},
+				},
+			}}}},
+			"responses": withStatus(obj{"200": obj{
+				"description": "The matches.",
+				"content":     obj{"application/json": obj{"schema": obj{"$ref": "#/components/schemas/SearchResult"}}},
+			}}, "400", "The request or its pattern is invalid."),
+		}},
+		"/graphql": obj{"post": obj{
+			"summary": "A GraphQL query of stories, searches, items and users.",
+			"requestBody": obj{"required": true, "content": obj{"application/json": obj{"schema": obj{
+				"type": "object",
+				"properties": obj{
+					"query":     obj{"type": "string"},
+					"variables": obj{"type": "object"},
+				},
+				"required": []string{"query"},
+			}}}},
+			"responses": obj{"200": obj{
+				"description": "The data or the errors of the query.",
+				"content":     obj{"application/json": obj{"schema": obj{"type": "object"}}},
+			}},
+		}},
+		"/events": obj{"get": obj{
+			"summary":    "The matches of a search, and then the new ones as they appear, as server-sent events.",
+			"parameters": liveParams,
+			"responses": withStatus(obj{"200": obj{
+				"description": "An item event with the JSON of each match, and an error event with a JSON string when a search fails.",
+				"content":     obj{"text/event-stream": obj{"schema": obj{"type": "string"}}},
+			}}, "400", "The parameters are invalid."),
+		}},
+		"/ws": obj{"get": obj{
+			"summary":    "Like /events, over a WebSocket.",
+			"parameters": liveParams,
+			"responses": withStatus(obj{"101": obj{
+				"description": `Switching to a WebSocket, with messages such as {"type": "item", "item": {...}} and {"type": "error", "error": "..."}.`,
+			}}, "400", "The parameters are invalid, or the request is not a WebSocket handshake."),
+		}},
+		"/threads.ics": obj{"get": obj{
+			"summary": "The monthly threads, such as Who is hiring?, as a calendar.",
+			"responses": obj{"200": obj{
+				"description": "An iCalendar feed.",
+				"content":     obj{"text/calendar": obj{"schema": obj{"type": "string"}}},
+			}},
+		}},
+		"/newest":    hnrss("The newest stories, as a feed."),
+		"/best":      hnrss("The best stories, as a feed."),
+		"/frontpage": hnrss("The stories on the front page, as a feed."),
+		"/user": obj{"get": obj{
+			"summary": "The submissions and comments of a user, as a feed.",
+			"parameters": append([]any{
+				param("id", "query", "The name of the user.", obj{"type": "string"}),
+			}, feedParams...),
+			"responses": withStatus(rss, "404", "There is no user with that name."),
+		}},
+	}
+	for _, p := range paths {
+		for _, op := range p.(obj) {
+			op := op.(obj)
+			op["responses"] = withStatus(op["responses"].(obj), "429", "Too many requests; see the Retry-After header.")
+		}
+	}
+	doc := obj{
+		"openapi": "3.0.3",
+		"info": obj{
+			"title":   "news",
+			"version": "1",
+			"description": "Searches of Hacker News stories. Requests for the stories " +
+				"fail with 502 Bad Gateway when the Hacker News API does.",
+		},
+		"servers": []any{obj{"url": server}},
+		"paths":   paths,
+		"components": obj{"schemas": obj{
+			"Item":         schemaOf(reflect.TypeFor[item]()),
+			"SearchResult": schemaOf(reflect.TypeFor[searchResult]()),
+		}},
+	}
+	if len(cfg().Keys) > 0 {
+		doc["components"].(obj)["securitySchemes"] = obj{
+			"bearer": obj{"type": "http", "scheme": "bearer"},
+			"query":  obj{"type": "apiKey", "in": "query", "name": "key"},
+		}
+		doc["security"] = []any{obj{"bearer": []string{}}, obj{"query": []string{}}}
+	}
+	return doc
+}
+
+func param(name, in, description string, schema obj) obj {
+	return obj{"name": name, "in": in, "description": description, "required": in == "path" || name == "id", "schema": schema}
+}
+
+// withStatus returns a copy of the responses with one more.
+func withStatus(responses obj, status, description string) obj {
+	r := maps.Clone(responses)
+	r[status] = obj{"description": description}
+	return r
+}
+
+// schemaOf returns the schema of the JSON encoding of the values of t.
+func schemaOf(t reflect.Type) obj {
+	switch t.Kind() {
+	case reflect.Bool:
+		return obj{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return obj{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return obj{"type": "number"}
+	case reflect.String:
+		return obj{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem() == reflect.TypeFor[item]() {
+			return obj{"type": "array", "items": obj{"$ref": "#/components/schemas/Item"}}
+		}
+		return obj{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.Struct:
+		props := obj{}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			props[cmp.Or(name, f.Name)] = schemaOf(f.Type)
+		}
+		return obj{"type": "object", "properties": props}
+	}
+	return obj{}
+}
@@ -0,0 +1,325 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// An output is where -out sends the matches of a search: a format and a
+// destination, given as FORMAT, FORMAT:DEST or just a file whose
+// extension tells the format. Files whose name ends in .gz are written
+// compressed with gzip, as results.json.gz, which is JSON.
+type output struct {
+	format string
+	dest   string // file, or URL for webhook; "" or "-" is standard output
+}
+
+// outputs are those given with -out, parsed by setup.
+var outputs []output
+
+// outputWriters maps the formats that can be written to a file or to
+// standard output to their writers.
+var outputWriters = map[string]func(io.Writer, *searchResult) error{
+	"html":  printHTML,
+	"text":  printText,
+	"json":  writeJSON,
+	"jsonl": writeJSONLines,
+	"tsv":   writeTSV,
+	"raw":   writeRaw,
+	"rss": func(w io.Writer, r *searchResult) error {
+		return writeRSS(w, "news", "Hacker News stories matching "+r.pattern(), r.Items)
+	},
+}
+
+// outputKinds maps file extensions to the format they imply. HTML files
+// get the report rather than bookmarks, which -out needs named.
+var outputKinds = map[string]string{
+	".html":    "html",
+	".htm":     "html",
+	".txt":     "text",
+	".json":    "json",
+	".jsonl":   "jsonl",
+	".ndjson":  "jsonl",
+	".tsv":     "tsv",
+	".rss":     "rss",
+	".xml":     "rss",
+	".db":      "sqlite",
+	".sqlite":  "sqlite",
+	".sqlite3": "sqlite",
+	".parquet": "parquet",
+}
+
+// parseOutput parses the -out specification spec.
+func parseOutput(spec string) (output, error) {
+	format, dest, _ := strings.Cut(spec, ":")
+	switch {
+	case outputWriters[format] != nil:
+		return output{format, dest}, nil
+	case format == "sqlite" || format == "parquet" || format == "bookmarks":
+		if dest == "" {
+			return output{}, fmt.Errorf("invalid -out %q: want %s:FILE", spec, format)
+		}
+		if strings.HasSuffix(dest, ".gz") {
+			return output{}, fmt.Errorf("invalid -out %q: %s files cannot be compressed", spec, format)
+		}
+		return output{format, dest}, nil
+	case format == "webhook":
+		if u, err := url.Parse(dest); err != nil || u.Scheme != "http" && u.Scheme != "https" {
+			return output{}, fmt.Errorf("invalid -out %q: want webhook:URL", spec)
+		}
+		return output{format, dest}, nil
+	}
+	if kind := outputKinds[filepath.Ext(strings.TrimSuffix(spec, ".gz"))]; kind != "" {
+		if strings.HasSuffix(spec, ".gz") && outputWriters[kind] == nil {
+			return output{}, fmt.Errorf("invalid -out %q: %s files cannot be compressed", spec, kind)
+		}
+		return output{kind, spec}, nil
+	}
+	return output{}, fmt.Errorf("invalid -out %q: want FORMAT[:DEST] or a FILE whose extension tells the format", spec)
+}
+
+// write writes the matches of the search s, whose result is r, to o.
+func (o output) write(s savedSearch, r *searchResult) error {
+	switch o.format {
+	case "sqlite", "parquet", "bookmarks":
+		return export(o.format+":"+o.dest, s.List, r)
+	case "webhook":
+		return postWebhook(o.dest, s.Name, r)
+	}
+	write := outputWriters[o.format]
+	if !o.toFile() {
+		return write(os.Stdout, r)
+	}
+	if *appendOut {
+		old, err := o.read()
+		if err != nil {
+			return err
+		}
+		merged := *r
+		merged.Items = mergeItems(old, r.Items)
+		merged.Total = len(merged.Items)
+		r = &merged
+	}
+	return writeFileAtomic(o.dest, func(w io.Writer) error {
+		if !strings.HasSuffix(o.dest, ".gz") {
+			return write(w, r)
+		}
+		zw := gzip.NewWriter(w)
+		zw.Name = strings.TrimSuffix(filepath.Base(o.dest), ".gz")
+		zw.ModTime = time.Now()
+		if err := write(zw, r); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+// toFile reports whether o is written to a file.
+func (o output) toFile() bool {
+	return o.dest != "" && o.dest != "-"
+}
+
+// appendable reports whether -append can merge matches into o.
+func (o output) appendable() bool {
+	return !o.toFile() || o.format == "json" || o.format == "jsonl" || o.format == "sqlite" || o.format == "webhook"
+}
+
+// read reads the stories in the json or jsonl file of o, if it exists.
+func (o output) read() ([]item, error) {
+	f, err := os.Open(o.dest)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(o.dest, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	}
+	var items []item
+	dec := json.NewDecoder(r)
+	if o.format == "json" {
+		if err := dec.Decode(&items); err != nil {
+			return nil, fmt.Errorf("%s: %v", o.dest, err)
+		}
+		return items, nil
+	}
+	for {
+		var it item
+		if err := dec.Decode(&it); err == io.EOF {
+			return items, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %v", o.dest, err)
+		}
+		items = append(items, it)
+	}
+}
+
+// mergeItems returns the stories of old, replaced by those of items with
+// the same ID, followed by the rest of items.
+func mergeItems(old, items []item) []item {
+	index := make(map[int]int, len(items))
+	for i := range items {
+		index[items[i].ID] = i
+	}
+	merged := make([]item, 0, len(old)+len(items))
+	for _, it := range old {
+		if i, ok := index[it.ID]; ok {
+			it = items[i]
+			delete(index, it.ID)
+		}
+		merged = append(merged, it)
+	}
+	for _, it := range items {
+		if _, ok := index[it.ID]; ok {
+			merged = append(merged, it)
+		}
+	}
+	return merged
+}
+
+// writeOutputs writes the result of the search s to each of outputs,
+// even if some fail, and returns the first error.
+func writeOutputs(outputs []output, s savedSearch, r *searchResult) error {
+	var first error
+	for _, o := range outputs {
+		if err := o.write(s, r); err != nil {
+			err = fmt.Errorf("-out %s: %v", o.format, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// pattern returns the source of the pattern of r, or "" if it has none.
+func (r *searchResult) pattern() string {
+	if r.re == nil {
+		return ""
+	}
+	return r.re.String()
+}
+
+// writeJSON writes the items of r to w as a JSON array.
+func writeJSON(w io.Writer, r *searchResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	items := r.Items
+	if items == nil {
+		items = []item{}
+	}
+	return enc.Encode(items)
+}
+
+// writeJSONLines writes the items of r to w as JSON objects, one a line.
+func writeJSONLines(w io.Writer, r *searchResult) error {
+	enc := json.NewEncoder(w)
+	for i := range r.Items {
+		if err := enc.Encode(&r.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRaw writes the items of r to w as -raw prints them.
+func writeRaw(w io.Writer, r *searchResult) error {
+	for i := range r.Items {
+		if err := writeRawItem(w, &r.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRawItem writes it to w as the JSON the API returned for it, on a
+// line. Items that were not fetched in this run, such as those resumed
+// with -resume, are encoded again, with the fields in Extra.
+func writeRawItem(w io.Writer, it *item) error {
+	data := it.raw
+	if data == nil {
+		var err error
+		if data, err = json.Marshal(it); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// writeTSV writes the items of r to w as the lines -stream prints.
+func writeTSV(w io.Writer, r *searchResult) error {
+	for i := range r.Items {
+		streamItem(w, &r.Items[i])
+	}
+	return nil
+}
+
+// webhookTimeout bounds the time a webhook takes to take the matches.
+const webhookTimeout = 30 * time.Second
+
+// postWebhook posts the matches of r to endpoint as a JSON object with
+// the name of the search, if any, the pattern, the total and the items.
+func postWebhook(endpoint, search string, r *searchResult) error {
+	body, err := json.Marshal(map[string]any{
+		"search":  search,
+		"pattern": r.pattern(),
+		"total":   r.Total,
+		"items":   r.Items,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// listFlag is a flag that can be repeated, collecting its values.
+type listFlag []string
+
+func newListFlag(name, usage string) *listFlag {
+	l := new(listFlag)
+	flag.Var(l, name, usage)
+	return l
+}
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
@@ -0,0 +1,268 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strings"
+)
+
+// This file implements just enough of the Parquet format to write a table
+// of items: a single row group of required columns, each stored as one
+// uncompressed, PLAIN-encoded data page. See
+// https://github.com/apache/parquet-format for the specification.
+
+// Parquet physical types, converted types and other enums used here.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetRequired = 0
+	parquetPlain    = 0
+	parquetRLE      = 3
+	parquetDataPage = 0
+)
+
+// A parquetColumn is a column of a table being written.
+type parquetColumn struct {
+	name      string
+	typ       int
+	converted int // -1 for none
+	values    bytes.Buffer
+	n         int  // number of values
+	bits      byte // booleans not yet written to values
+}
+
+func (c *parquetColumn) int64(v int64) {
+	binary.Write(&c.values, binary.LittleEndian, v)
+	c.n++
+}
+
+func (c *parquetColumn) string(s string) {
+	binary.Write(&c.values, binary.LittleEndian, uint32(len(s)))
+	c.values.WriteString(s)
+	c.n++
+}
+
+// bool appends b. Booleans are packed eight to a byte, low bit first.
+func (c *parquetColumn) bool(b bool) {
+	if b {
+		c.bits |= 1 << (c.n % 8)
+	}
+	c.n++
+	if c.n%8 == 0 {
+		c.values.WriteByte(c.bits)
+		c.bits = 0
+	}
+}
+
+// flush writes a partially filled byte of booleans.
+func (c *parquetColumn) flush() {
+	if c.typ == parquetBoolean && c.n%8 != 0 {
+		c.values.WriteByte(c.bits)
+		c.bits = 0
+	}
+}
+
+// exportParquet writes the items of r to the Parquet file.
+func exportParquet(file string, r *searchResult) error {
+	cols := []*parquetColumn{
+		{name: "id", typ: parquetInt64, converted: -1},
+		{name: "type", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "by", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "time", typ: parquetInt64, converted: parquetTimestampMillis},
+		{name: "title", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "url", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "text", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "score", typ: parquetInt64, converted: -1},
+		{name: "descendants", typ: parquetInt64, converted: -1},
+		{name: "dead", typ: parquetBoolean, converted: -1},
+		{name: "deleted", typ: parquetBoolean, converted: -1},
+		{name: "labels", typ: parquetByteArray, converted: parquetUTF8}, // comma-separated
+	}
+	for i := range r.Items {
+		it := &r.Items[i]
+		cols[0].int64(int64(it.ID))
+		cols[1].string(it.Type)
+		cols[2].string(it.By)
+		cols[3].int64(it.Time * 1000)
+		cols[4].string(it.PlainTitle())
+		cols[5].string(it.URL)
+		cols[6].string(it.Text)
+		cols[7].int64(int64(it.Score))
+		cols[8].int64(int64(it.Descendants))
+		cols[9].bool(it.Dead)
+		cols[10].bool(it.Deleted)
+		cols[11].string(strings.Join(it.Labels, ","))
+	}
+
+	var out bytes.Buffer
+	out.WriteString("PAR1")
+	offsets := make([]int, len(cols))
+	sizes := make([]int, len(cols))
+	total := 0
+	for i, c := range cols {
+		c.flush()
+		var page thrift
+		page.i32(1, parquetDataPage)
+		page.i32(2, int32(c.values.Len()))
+		page.i32(3, int32(c.values.Len()))
+		page.structField(5)
+		page.i32(1, int32(c.n))
+		page.i32(2, parquetPlain)
+		page.i32(3, parquetRLE)
+		page.i32(4, parquetRLE)
+		page.endStruct()
+		page.WriteByte(0)
+
+		offsets[i] = out.Len()
+		out.Write(page.Bytes())
+		out.Write(c.values.Bytes())
+		sizes[i] = out.Len() - offsets[i]
+		total += sizes[i]
+	}
+
+	// FileMetaData
+	var meta thrift
+	meta.i32(1, 1)
+	meta.listField(2, len(cols)+1, thriftStruct)
+	meta.beginStruct()
+	meta.fieldBinary(4, "schema")
+	meta.i32(5, int32(len(cols)))
+	meta.endStruct()
+	for _, c := range cols {
+		meta.beginStruct()
+		meta.i32(1, int32(c.typ))
+		meta.i32(3, parquetRequired)
+		meta.fieldBinary(4, c.name)
+		if c.converted >= 0 {
+			meta.i32(6, int32(c.converted))
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, int64(len(r.Items)))
+	meta.listField(4, 1, thriftStruct)
+	meta.beginStruct() // RowGroup
+	meta.listField(1, len(cols), thriftStruct)
+	for i, c := range cols {
+		meta.beginStruct() // ColumnChunk
+		meta.i64(2, int64(offsets[i]))
+		meta.structField(3) // ColumnMetaData
+		meta.i32(1, int32(c.typ))
+		meta.listField(2, 1, thriftI32)
+		meta.varint(zigzag(parquetPlain))
+		meta.listField(3, 1, thriftBinary)
+		meta.binary(c.name)
+		meta.i32(4, 0) // uncompressed
+		meta.i64(5, int64(c.n))
+		meta.i64(6, int64(sizes[i]))
+		meta.i64(7, int64(sizes[i]))
+		meta.i64(9, int64(offsets[i]))
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(2, int64(total))
+	meta.i64(3, int64(len(r.Items)))
+	meta.endStruct()
+	meta.fieldBinary(6, "news")
+	meta.WriteByte(0)
+
+	out.Write(meta.Bytes())
+	binary.Write(&out, binary.LittleEndian, uint32(meta.Len()))
+	out.WriteString("PAR1")
+	return os.WriteFile(file, out.Bytes(), 0o644)
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thrift encodes structs with the Thrift compact protocol, which Parquet
+// uses for its metadata. Fields must be written in increasing ID order.
+type thrift struct {
+	bytes.Buffer
+	last  int   // ID of the last field written in the current struct
+	stack []int // last field IDs of the enclosing structs
+}
+
+func (t *thrift) varint(v uint64) {
+	t.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// field writes the header of field id of type typ.
+func (t *thrift) field(id int, typ byte) {
+	if d := id - t.last; d > 0 && d <= 15 {
+		t.WriteByte(byte(d)<<4 | typ)
+	} else {
+		t.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last = id
+}
+
+func (t *thrift) i32(id int, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thrift) i64(id int, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thrift) binary(s string) {
+	t.varint(uint64(len(s)))
+	t.WriteString(s)
+}
+
+func (t *thrift) fieldBinary(id int, s string) {
+	t.field(id, thriftBinary)
+	t.binary(s)
+}
+
+// listField writes the header of list field id with n elements of type
+// elem. Struct elements are each written between beginStruct and
+// endStruct.
+func (t *thrift) listField(id, n int, elem byte) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	t.WriteByte(0xf0 | elem)
+	t.varint(uint64(n))
+}
+
+// structField writes the header of struct field id and begins the struct.
+func (t *thrift) structField(id int) {
+	t.field(id, thriftStruct)
+	t.beginStruct()
+}
+
+func (t *thrift) beginStruct() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thrift) endStruct() {
+	t.WriteByte(0)
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
@@ -0,0 +1,300 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// A pattern is a compiled search pattern. It is a regular expression,
+// matched against the title and text of items, unless it starts with the
+// name of a part of the story URL and a colon, such as
+//
+//	url.host:^github\.com$
+//	url.path:/rust-lang/
+//
+// when it is matched against that part alone. The parts are url, the
+// whole URL, url.host, url.path and url.query. With -glob, the regular
+// expressions are translated from shell-style wildcards (see globRegexp),
+// and with -fuzzy or -stem, patterns not scoped to a field are fuzzy
+// terms or stemmed phrases instead.
+type pattern struct {
+	*regexp.Regexp                // matched against the title and text; nil if none
+	terms          []textMatcher  // likewise, with -fuzzy or -stem
+	fields         []fieldPattern // matched against parts of the URL
+}
+
+// A textMatcher matches titles and texts other than with a regular
+// expression.
+type textMatcher interface {
+	match(s string) bool
+	String() string
+}
+
+// A fieldPattern is a regular expression matched against a part of the
+// story URL.
+type fieldPattern struct {
+	field string
+	re    *regexp.Regexp
+}
+
+// urlFields are the parts of the story URL that patterns can be scoped to.
+var urlFields = []string{"url", "url.host", "url.path", "url.query"}
+
+// compile compiles the pattern s. The regular expression of patterns not
+// scoped to a field is normalized the same way as the text it will be
+// matched against.
+func compile(s string) (*pattern, error) {
+	for _, f := range urlFields {
+		if expr, ok := strings.CutPrefix(s, f+":"); ok {
+			re, err := compileExpr(expr)
+			if err != nil {
+				return nil, err
+			}
+			return &pattern{fields: []fieldPattern{{f, re}}}, nil
+		}
+	}
+	switch {
+	case *fuzzy > 0:
+		t, err := newFuzzyTerm(normalize(s, *fold), *fuzzy)
+		if err != nil {
+			return nil, err
+		}
+		return &pattern{terms: []textMatcher{t}}, nil
+	case *stem:
+		p, err := newStemPhrase(normalize(s, *fold))
+		if err != nil {
+			return nil, err
+		}
+		return &pattern{terms: []textMatcher{p}}, nil
+	}
+	if !*glob {
+		s = expandSynonyms(s, cfg().Synonyms)
+	}
+	re, err := compileExpr(normalizePattern(s, *fold))
+	if err != nil {
+		return nil, err
+	}
+	return &pattern{Regexp: re}, nil
+}
+
+// expandSynonyms replaces each whole word of the regular expression expr
+// that is in synonyms, which the configuration file sets as in
+//
+//	"synonyms": {"golang": "golang|\\bgo\\b", "k8s": "k8s|kubernetes"}
+//
+// with the expression it maps to, so that saved searches and watchlists
+// can say golang rather than spell out every way to write it. The words
+// of the expressions are not expanded again.
+func expandSynonyms(expr string, synonyms map[string]string) string {
+	if len(synonyms) == 0 {
+		return expr
+	}
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		if expr[i] == '\\' && i+1 < len(expr) {
+			// Keep escapes such as \b and \d whole.
+			b.WriteString(expr[i : i+2])
+			i += 2
+			continue
+		}
+		j := i
+		for j < len(expr) && isWordByte(expr[j]) {
+			j++
+		}
+		if syn, ok := synonyms[expr[i:j]]; ok && j > i {
+			b.WriteString("(?:" + syn + ")")
+			i = j
+			continue
+		}
+		if j == i {
+			j++
+		}
+		b.WriteString(expr[i:j])
+		i = j
+	}
+	return b.String()
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// isWord reports whether s is a word, as expandSynonyms finds them.
+func isWord(s string) bool {
+	for i := range len(s) {
+		if !isWordByte(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// compileExpr compiles expr as a regular expression or, with -glob, as a
+// shell-style wildcard.
+func compileExpr(expr string) (*regexp.Regexp, error) {
+	if *glob {
+		var err error
+		if expr, err = globRegexp(expr); err != nil {
+			return nil, err
+		}
+	}
+	return regexp.Compile(expr)
+}
+
+// globRegexp translates the shell-style wildcard glob into a regular
+// expression matching the same whole strings: * matches any text, ? any
+// character, [...] any character in the brackets, or not in them if the
+// first is ! or ^, and a backslash quotes the next character.
+func globRegexp(glob string) (string, error) {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i++; i == len(glob) {
+				return "", fmt.Errorf("invalid glob %q: trailing backslash", glob)
+			}
+			_, n := utf8.DecodeRuneInString(glob[i:])
+			b.WriteString(regexp.QuoteMeta(glob[i : i+n]))
+			i += n - 1
+		case '[':
+			// The first character of the class, even ], is part of it.
+			j := i + 1
+			if j < len(glob) && (glob[j] == '!' || glob[j] == '^') {
+				j++
+			}
+			if j < len(glob) && glob[j] == ']' {
+				j++
+			}
+			end := strings.IndexByte(glob[j:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("invalid glob %q: missing ]", glob)
+			}
+			class := glob[i+1 : j+end]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, "[", `\[`) + "]")
+			i = j + end
+		default:
+			_, n := utf8.DecodeRuneInString(glob[i:])
+			b.WriteString(regexp.QuoteMeta(glob[i : i+n]))
+			i += n - 1
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// union returns a pattern that matches whatever any of ps does.
+func union(ps []*pattern) (*pattern, error) {
+	u := new(pattern)
+	var alts []string
+	for _, p := range ps {
+		if p.Regexp != nil {
+			alts = append(alts, "(?:"+p.Regexp.String()+")")
+		}
+		u.terms = append(u.terms, p.terms...)
+		u.fields = append(u.fields, p.fields...)
+	}
+	if len(alts) > 0 {
+		var err error
+		if u.Regexp, err = regexp.Compile(strings.Join(alts, "|")); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// String returns the source of p, with the field of each part.
+func (p *pattern) String() string {
+	var parts []string
+	if p.Regexp != nil {
+		parts = append(parts, p.Regexp.String())
+	}
+	for _, t := range p.terms {
+		parts = append(parts, t.String())
+	}
+	for _, f := range p.fields {
+		parts = append(parts, f.field+":"+f.re.String())
+	}
+	return strings.Join(parts, " | ")
+}
+
+// matchText reports whether p matches the normalized text s, a title or
+// the text of an item.
+func (p *pattern) matchText(s string) bool {
+	if p.Regexp != nil && p.MatchString(s) {
+		return true
+	}
+	for _, t := range p.terms {
+		if t.match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// findAll returns the parts of the title, text, page text and URL of it
+// that p matches, in that order. Fuzzy terms and stemmed phrases match no
+// parts.
+func (p *pattern) findAll(it *item) []string {
+	var parts []string
+	if p.Regexp != nil {
+		for _, s := range []string{it.PlainTitle(), it.PlainText(), it.Content} {
+			parts = append(parts, p.FindAllString(normalize(s, *fold), -1)...)
+		}
+	}
+	for _, f := range p.fields {
+		parts = append(parts, f.re.FindAllString(urlField(it, f.field), -1)...)
+	}
+	return parts
+}
+
+// titleOnly reports whether p is matched against the title and text
+// alone, and so can be used to look at titles before fetching items.
+func (p *pattern) titleOnly() bool {
+	return len(p.fields) == 0
+}
+
+// matchesURL reports whether the field patterns of p match the URL of it.
+func (p *pattern) matchesURL(it *item) bool {
+	for _, f := range p.fields {
+		if f.re.MatchString(urlField(it, f.field)) {
+			return true
+		}
+	}
+	return false
+}
+
+// urlField returns the part of the story URL of it named by field, one
+// of urlFields.
+func urlField(it *item, field string) string {
+	if field == "url" {
+		return it.URL
+	}
+	u, err := url.Parse(it.URL)
+	if err != nil {
+		return ""
+	}
+	switch field {
+	case "url.host":
+		return strings.ToLower(u.Hostname())
+	case "url.path":
+		return u.Path
+	case "url.query":
+		return u.RawQuery
+	}
+	return ""
+}
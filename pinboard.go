@@ -0,0 +1,80 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// pinboardInterval is the minimum time between API calls that Pinboard
+// allows (https://pinboard.in/api/).
+const pinboardInterval = 3 * time.Second
+
+// pinboard saves stories as Pinboard bookmarks.
+type pinboard struct {
+	token string
+	last  time.Time // of the last call
+}
+
+func newPinboard(c *config) (sink, error) {
+	token := c.Pinboard.Token
+	if token == "" {
+		token = os.Getenv("PINBOARD_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("no API token: set pinboard.token in the config file or $PINBOARD_TOKEN")
+	}
+	return &pinboard{token: token}, nil
+}
+
+func (p *pinboard) save(it *item, tags []string) error {
+	if wait := pinboardInterval - time.Since(p.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	p.last = time.Now()
+
+	q := url.Values{
+		"auth_token":  {p.token},
+		"format":      {"json"},
+		"url":         {storyURL(it)},
+		"description": {it.PlainTitle()},
+		"extended":    {"HN discussion: " + itemURL(it.ID)},
+		"tags":        {strings.Join(tags, " ")},
+		"dt":          {it.Created().UTC().Format(time.RFC3339)},
+		"replace":     {"no"},
+	}
+	resp, err := http.Get("https://api.pinboard.in/v1/posts/add?" + q.Encode())
+	if err != nil {
+		// The URL, in the message of a url.Error, has the token, which
+		// would end up in logs and the queue.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			return fmt.Errorf("posts/add: %w", uerr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	var result struct {
+		Code string `json:"result_code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	switch result.Code {
+	case "done", "item already exists":
+		return nil
+	}
+	return errors.New(result.Code)
+}
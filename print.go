@@ -0,0 +1,216 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// formatTime formats t according to the -time-format flag.
+func formatTime(t time.Time) string {
+	if *timeFormat == "absolute" {
+		return t.In(location).Format("2006-01-02 15:04 MST")
+	}
+	return relativeTime(time.Since(t))
+}
+
+// relativeTime returns a short human description of the age d,
+// such as "5m ago" or "3d ago".
+func relativeTime(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	}
+	return fmt.Sprintf("%dy ago", int(d/(365*24*time.Hour)))
+}
+
+// printText writes r to w as a plain-text table. The text of an item, if
+// any, follows its row, converted from HTML and indented.
+func printText(w io.Writer, r *searchResult) error {
+	header := []string{"ID", "POINTS", "COMMENTS", "AUTHOR", "TIME"}
+	if *frontPageRank {
+		header = append(header, "RANK")
+	}
+	rows := [][]string{append(header, "TITLE")}
+	for i := range r.Items {
+		it := &r.Items[i]
+		title := it.PlainTitle()
+		if host := hostname(it.URL); host != "" {
+			title += " (" + host + ")"
+		}
+		row := []string{
+			strconv.Itoa(it.ID),
+			strconv.Itoa(it.Score),
+			strconv.Itoa(it.Descendants),
+			it.By,
+			formatTime(it.Created()),
+		}
+		if *frontPageRank {
+			row = append(row, formatRank(it.Rank, it.RankEstimated))
+		}
+		rows = append(rows, append(row, title))
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	// The title goes last and gets whatever room the other columns leave.
+	margin := 0
+	for _, w := range widths[:len(widths)-1] {
+		margin += w + 2
+	}
+	width := outputWidth(w)
+	titleWidth := 0
+	if width > 0 {
+		titleWidth = max(width-margin, minTitleWidth)
+	}
+	textWidth := maxTextWidth
+	if width > 0 {
+		textWidth = min(textWidth, width)
+	}
+	p := newPainter(w)
+	styles := []string{"", p.score, "", p.author, p.time, ""}
+	for i, row := range rows {
+		var b strings.Builder
+		for j, cell := range row[:len(row)-1] {
+			if i > 0 {
+				cell = p.paint(styles[j], cell)
+			}
+			b.WriteString(cell + strings.Repeat(" ", widths[j]-utf8.RuneCountInString(row[j])+2))
+		}
+		title := row[len(row)-1]
+		switch {
+		case titleWidth == 0:
+		case *wrapTitles:
+			title = wrap(title, titleWidth)
+		default:
+			title = truncate(title, titleWidth)
+		}
+		for k, line := range strings.Split(title, "\n") {
+			if k > 0 {
+				b.WriteString(strings.Repeat(" ", margin))
+			}
+			if i > 0 {
+				line = p.highlight(r.re, line)
+			}
+			b.WriteString(line + "\n")
+		}
+		if i > 0 && r.Items[i-1].Snippet != "" {
+			snippet := p.highlight(r.re, r.Items[i-1].Snippet)
+			b.WriteString(strings.Repeat(" ", margin) + snippet + "\n")
+		}
+		if i > 0 && len(r.Items[i-1].Labels) > 0 {
+			b.WriteString(strings.Repeat(" ", margin) + "labels: " + strings.Join(r.Items[i-1].Labels, ", ") + "\n")
+		}
+		if i > 0 && r.Items[i-1].Author != nil {
+			b.WriteString(strings.Repeat(" ", margin) + "author: " + r.Items[i-1].Author.String() + "\n")
+		}
+		if i > 0 && r.Items[i-1].Archive != "" {
+			b.WriteString(strings.Repeat(" ", margin) + "archive: " + r.Items[i-1].Archive + "\n")
+		}
+		if i > 0 && r.Items[i-1].Summary != "" {
+			const indent = "    "
+			for _, line := range strings.Split(wrap(r.Items[i-1].Summary, textWidth-len(indent)), "\n") {
+				b.WriteString(indent + line + "\n")
+			}
+			b.WriteString("\n")
+		}
+		if i > 0 && r.Items[i-1].Text != "" {
+			const indent = "    "
+			for _, line := range strings.Split(htmlToText(r.Items[i-1].Text, textWidth-len(indent)), "\n") {
+				if line != "" {
+					line = indent + line
+				}
+				b.WriteString(line + "\n")
+			}
+			b.WriteString("\n")
+		}
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	maxTextWidth  = 80 // width at which item texts are wrapped
+	minTitleWidth = 20 // the title column never gets narrower than this
+)
+
+// outputWidth returns the width that the plain-text table written to w
+// should fit in, or 0 if titles should be printed in full. Unless -wrap or
+// -truncate is given, titles are only shortened when writing to a
+// terminal.
+func outputWidth(w io.Writer) int {
+	if f, ok := w.(*os.File); ok {
+		if n := terminalWidth(f); n > 0 {
+			return n
+		}
+	}
+	if !*wrapTitles && !*truncateTitles {
+		return 0
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// hostname returns the host part of rawURL without a leading "www.",
+// or "" if rawURL is not a valid absolute URL.
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// sparklineWidth is the number of values shown in a sparkline.
+const sparklineWidth = 24
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as a line of block characters, one per value,
+// scaled between the smallest and largest of them.
+func sparkline(values []int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := slices.Min(values), slices.Max(values)
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if hi > lo {
+			level = (v - lo) * (len(sparks) - 1) / (hi - lo)
+		}
+		line[i] = sparks[level]
+	}
+	return string(line)
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// A progress records, with -resume, the stories a search has checked in a
+// file, a JSON object a line: first the list and pattern searched, then
+// each story checked, with the item if it matched. A run that stops
+// before its outputs are written leaves the file behind, and the next run
+// of the same search reads it back to skip the stories already checked.
+type progress struct {
+	file  string
+	f     *os.File
+	enc   *json.Encoder
+	done  map[int]bool // stories already checked
+	items []item       // those that matched
+}
+
+type progressHeader struct {
+	List    string `json:"list"`
+	Pattern string `json:"pattern"`
+}
+
+type progressEntry struct {
+	ID   int   `json:"id"`
+	Item *item `json:"item,omitempty"` // if it matched
+}
+
+// resume is the progress of the search of this run, if -resume is set.
+var resume *progress
+
+// openProgress opens the progress file of the search of list for re,
+// creating it if it does not exist. It is an error for the file to be
+// that of another search.
+func openProgress(file, list string, re *pattern) (*progress, error) {
+	want := progressHeader{List: list}
+	if re != nil {
+		want.Pattern = re.String()
+	}
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	p := &progress{file: file, f: f, enc: json.NewEncoder(f), done: make(map[int]bool)}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if err := p.enc.Encode(want); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return p, nil
+	}
+	var header progressHeader
+	if err := json.Unmarshal(sc.Bytes(), &header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if header != want {
+		f.Close()
+		return nil, fmt.Errorf("%s: progress of another search, of %s for %q; remove it to start over", file, header.List, header.Pattern)
+	}
+	for sc.Scan() {
+		var e progressEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil || p.done[e.ID] {
+			continue // cut short when the run stopped, or a duplicate
+		}
+		p.done[e.ID] = true
+		if e.Item != nil {
+			p.items = append(p.items, *e.Item)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	// End a line cut short, so that the next entry starts its own.
+	end := make([]byte, 1)
+	if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+		if _, err := f.ReadAt(end, fi.Size()-1); err == nil && end[0] != '\n' {
+			if _, err := f.WriteString("\n"); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// add records that the story it was checked, and whether it matched.
+// A nil p records nothing.
+func (p *progress) add(it *item, matched bool) error {
+	if p == nil {
+		return nil
+	}
+	e := progressEntry{ID: it.ID}
+	if matched {
+		e.Item = it
+	}
+	return p.enc.Encode(e)
+}
+
+// finish removes the progress file, once the run is complete.
+func (p *progress) finish() error {
+	return errors.Join(p.f.Close(), os.Remove(p.file))
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// runPublish implements the publish command, which renders the saved
+// searches named in args, or all of them, into a static site in dir: an
+// index linking to a page and an RSS feed of the matches of each search.
+// The site needs no server, so a scheduled job can push it to GitHub
+// Pages or the like. Each file is replaced only once it is complete.
+func runPublish(args []string) {
+	if len(args) == 0 {
+		fatal(fmt.Errorf("usage: news publish DIR [SEARCH...]"))
+	}
+	dir, names := args[0], args[1:]
+	var list []savedSearch
+	if len(names) == 0 {
+		list = cfg().Searches
+	}
+	for _, name := range names {
+		s, err := cfg().search(name)
+		if err != nil {
+			fatal(err)
+		}
+		list = append(list, s)
+	}
+	if len(list) == 0 {
+		fatal(fmt.Errorf("no saved searches to publish"))
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fatal(err)
+	}
+	var index []publishedSearch
+	used := map[string]bool{"index": true}
+	for _, s := range list {
+		if s.List == "" {
+			s.List = "new"
+		}
+		result, err := runSearch(s)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %v", s.Name, err))
+		}
+		base := slug(s.Name)
+		for i := 2; used[base]; i++ {
+			base = fmt.Sprintf("%s-%d", slug(s.Name), i)
+		}
+		used[base] = true
+		p := publishedSearch{savedSearch: s, Total: result.Total, Page: base + ".html", Feed: base + ".xml"}
+		err = writeFileAtomic(filepath.Join(dir, p.Page), func(w io.Writer) error {
+			return printHTML(w, result)
+		})
+		if err != nil {
+			fatal(err)
+		}
+		err = writeFileAtomic(filepath.Join(dir, p.Feed), func(w io.Writer) error {
+			return writeRSS(w, "news: "+s.Name, "Hacker News stories matching "+s.Pattern, result.Items)
+		})
+		if err != nil {
+			fatal(err)
+		}
+		index = append(index, p)
+	}
+	err := writeFileAtomic(filepath.Join(dir, "index.html"), func(w io.Writer) error {
+		return publishTemplate.Execute(w, map[string]any{"Searches": index, "Updated": time.Now()})
+	})
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// A publishedSearch is an entry of the index of a published site.
+type publishedSearch struct {
+	savedSearch
+	Total int
+	Page  string // file name of the page of the matches
+	Feed  string // file name of their RSS feed
+}
+
+// slug returns a file name for the search name: its letters and digits,
+// in lower case, with dashes between the words.
+func slug(name string) string {
+	f := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(f) == 0 {
+		return "search"
+	}
+	return strings.Join(f, "-")
+}
+
+// writeFileAtomic replaces file with what write writes, leaving the old
+// file in place if it fails.
+func writeFileAtomic(file string, write func(io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+var publishTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"css":       func() template.CSS { return reportCSS[*themeName] },
+	"isoTime":   func(t time.Time) string { return t.In(location).Format(time.RFC3339) },
+	"localTime": func(t time.Time) string { return t.In(location).Format("2006-01-02 15:04 MST") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Hacker News searches</title>
+{{- range .Searches}}
+<link rel="alternate" type="application/rss+xml" title="{{.Name}}" href="{{.Feed}}">
+{{- end}}
+<style>
+body { font: 14px/1.4 Verdana, Geneva, sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 8px; text-align: left; vertical-align: top; }
+td.num { text-align: right; }
+a { text-decoration: none; }
+a:hover { text-decoration: underline; }
+code { font-size: 90%; }
+{{css}}
+</style>
+</head>
+<body>
+<h1>Hacker News searches</h1>
+<table>
+<thead>
+<tr>
+	<th>search</th>
+	<th>pattern</th>
+	<th>list</th>
+	<th>stories</th>
+	<th>feed</th>
+</tr>
+</thead>
+<tbody>
+{{- range .Searches}}
+<tr>
+	<td><a href="{{.Page}}">{{.Name}}</a></td>
+	<td><code>{{.Pattern}}</code></td>
+	<td>{{.List}}</td>
+	<td class="num">{{.Total}}</td>
+	<td><a href="{{.Feed}}">RSS</a></td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+<p>Updated <time datetime="{{isoTime .Updated}}">{{localTime .Updated}}</time>.</p>
+</body>
+</html>
+`))
@@ -0,0 +1,249 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Stories that cannot be saved to a sink, because the service is down
+// for instance, are not lost: they are kept in a queue, in a state file,
+// and tried again by later runs, and periodically by the serve command.
+// The time between attempts doubles after each failure, and stories that
+// fail too many times are given up on and written to a dead-letter file.
+// This is set in the "retry" section of the configuration file:
+//
+//	"retry": {"attempts": 8, "backoff": "1m", "max_backoff": "6h"}
+
+// The defaults of the retry settings.
+const (
+	defaultRetryAttempts   = 8
+	defaultRetryBackoff    = time.Minute
+	defaultRetryMaxBackoff = 6 * time.Hour
+)
+
+// A retryPolicy is the "retry" section of the configuration file.
+type retryPolicy struct {
+	Attempts   int    `json:"attempts,omitempty"`    // in all, including the first
+	Backoff    string `json:"backoff,omitempty"`     // after the first failure
+	MaxBackoff string `json:"max_backoff,omitempty"` // the most time between attempts
+}
+
+// check reports whether p is valid.
+func (p *retryPolicy) check() error {
+	if p.Attempts < 0 {
+		return fmt.Errorf("invalid attempts %d", p.Attempts)
+	}
+	for _, d := range []string{p.Backoff, p.MaxBackoff} {
+		if d == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(d); err != nil || v <= 0 {
+			return fmt.Errorf("invalid duration %q", d)
+		}
+	}
+	return nil
+}
+
+// backoff returns the time to wait after the given number of failed
+// attempts, or a negative duration to give up.
+func (p *retryPolicy) backoff(attempts int) time.Duration {
+	if attempts >= cmp.Or(p.Attempts, defaultRetryAttempts) {
+		return -1
+	}
+	d, _ := time.ParseDuration(p.Backoff)
+	d = cmp.Or(d, defaultRetryBackoff)
+	limit, _ := time.ParseDuration(p.MaxBackoff)
+	limit = cmp.Or(limit, defaultRetryMaxBackoff)
+	for range attempts - 1 {
+		if d *= 2; d >= limit {
+			break
+		}
+	}
+	return min(d, limit)
+}
+
+// A queued is a story waiting to be saved to a sink.
+type queued struct {
+	Sink     string   `json:"sink"`
+	Tags     []string `json:"tags"`
+	Item     item     `json:"item"`
+	Attempts int      `json:"attempts"` // failed so far
+	Next     int64    `json:"next"`     // Unix time of the next attempt
+	Error    string   `json:"error"`    // of the last attempt
+}
+
+// fail records a failed attempt with err, and reports whether to try
+// again.
+func (e *queued) fail(err error) bool {
+	e.Attempts++
+	e.Error = err.Error()
+	wait := cfg().Retry.backoff(e.Attempts)
+	if wait < 0 {
+		return false
+	}
+	e.Next = time.Now().Add(wait).Unix()
+	return true
+}
+
+// deadLetterFile returns the path of the file where the stories that are
+// given up on are written, one JSON object per line.
+func deadLetterFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "news-dead-letter.jsonl"
+	}
+	return filepath.Join(dir, "news", "dead-letter.jsonl")
+}
+
+// deadLetter logs that e is given up on and writes it to the
+// dead-letter file. It takes stateMu.
+func deadLetter(e queued) {
+	log.Printf("queue: %s: giving up on %d after %d attempts: %s", e.Sink, e.Item.ID, e.Attempts, e.Error)
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	file := deadLetterFile()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		log.Print(err)
+		return
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+// queueFile returns the path of the state file of the queue.
+func queueFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "news-queue.json"
+	}
+	return filepath.Join(dir, "news", "queue.json")
+}
+
+func loadQueue(file string) ([]queued, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var q []queued
+	if err := json.Unmarshal(data, &q); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return q, nil
+}
+
+func saveQueue(file string, q []queued) error {
+	if len(q) == 0 {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// enqueue adds the stories that failed to be saved to the queue. The
+// caller holds stateMu.
+func enqueue(failed []queued) error {
+	q, err := loadQueue(queueFile())
+	if err != nil {
+		return err
+	}
+	return saveQueue(queueFile(), append(q, failed...))
+}
+
+// retryQueued tries again to save the queued stories that are due, and
+// keeps those that fail again, unless they have run out of attempts.
+// stateMu is held only to read and write the queue, not while saving.
+func retryQueued() error {
+	stateMu.Lock()
+	q, err := loadQueue(queueFile())
+	var due, keep []queued
+	now := time.Now().Unix()
+	for _, e := range q {
+		if e.Next > now {
+			keep = append(keep, e)
+		} else {
+			due = append(due, e)
+		}
+	}
+	if err == nil && len(due) > 0 {
+		err = saveQueue(queueFile(), keep)
+	}
+	stateMu.Unlock()
+	if err != nil || len(due) == 0 {
+		return err
+	}
+	var again []queued
+	made := make(map[string]sink)
+	for _, e := range due {
+		s, ok := made[e.Sink]
+		if !ok {
+			newSink, known := sinks[e.Sink]
+			if !known {
+				log.Printf("queue: dropping %d for unknown sink %q", e.Item.ID, e.Sink)
+				continue
+			}
+			if s, err = newSink(cfg()); err != nil {
+				log.Printf("queue: %s: %v", e.Sink, err)
+			}
+			made[e.Sink] = s
+		}
+		if s == nil {
+			again = append(again, e)
+			continue
+		}
+		if err := s.save(&e.Item, e.Tags); err != nil {
+			if e.fail(err) {
+				again = append(again, e)
+			} else {
+				deadLetter(e)
+			}
+			continue
+		}
+		log.Printf("queue: %s: saved %d", e.Sink, e.Item.ID)
+	}
+	if len(again) == 0 {
+		return nil
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	return enqueue(again)
+}
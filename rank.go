@@ -0,0 +1,69 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// The weights of the parts of the relevance of a match, which add up to 1.
+const (
+	hitWeight      = 0.4
+	pointsWeight   = 0.25
+	recencyWeight  = 0.2
+	commentsWeight = 0.15
+)
+
+// rankItems sorts items, the matches of re, by relevance, most relevant
+// first; see relevance.
+func rankItems(items []item, re *pattern) {
+	now := time.Now()
+	rel := make(map[int]float64, len(items))
+	for i := range items {
+		rel[items[i].ID] = relevance(&items[i], re, now)
+	}
+	slices.SortStableFunc(items, func(a, b item) int {
+		return cmp.Compare(rel[b.ID], rel[a.ID])
+	})
+}
+
+// relevance scores how well it matches re, from 0 to 1, by blending how
+// much of its title re matches with its points, its age, halving every
+// day, and its number of comments. Points and comments count on a log
+// scale, saturating at 1000 and 500.
+func relevance(it *item, re *pattern, now time.Time) float64 {
+	points := math.Min(math.Log1p(float64(max(it.Score, 0)))/math.Log1p(1000), 1)
+	comments := math.Min(math.Log1p(float64(max(it.Descendants, 0)))/math.Log1p(500), 1)
+	age := max(now.Sub(it.Created()), 0)
+	recency := math.Exp2(-age.Hours() / 24)
+	return hitWeight*hitQuality(it, re) + pointsWeight*points +
+		recencyWeight*recency + commentsWeight*comments
+}
+
+// hitQuality scores how well re matches it, from 0 to 1: matches in the
+// title score best, the more of it they cover the better, and matches
+// only elsewhere, in the text, the page or the URL, score least.
+func hitQuality(it *item, re *pattern) float64 {
+	if re == nil {
+		return 0
+	}
+	title := normalize(it.PlainTitle(), *fold)
+	if re.Regexp != nil && title != "" {
+		covered := 0
+		for _, m := range re.FindAllStringIndex(title, -1) {
+			covered += m[1] - m[0]
+		}
+		if covered > 0 {
+			return 0.5 + 0.5*float64(covered)/float64(len(title))
+		}
+	}
+	if re.matchText(title) {
+		return 0.75
+	}
+	return 0.25
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A rate is a number of requests allowed in a period, written like 60/m.
+type rate struct {
+	n   int
+	per time.Duration
+}
+
+// parseRate parses a rate such as 60/m; the period is s, m, h or a
+// duration such as 10s.
+func parseRate(s string) (rate, error) {
+	num, unit, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(num)
+	if !ok || err != nil || n <= 0 {
+		return rate{}, fmt.Errorf("invalid rate %q", s)
+	}
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		if per, err = time.ParseDuration(unit); err != nil || per <= 0 {
+			return rate{}, fmt.Errorf("invalid rate %q", s)
+		}
+	}
+	return rate{n, per}, nil
+}
+
+// clients are the limits of requests of each API key and, for requests
+// without one, each IP address.
+var clients = newLimiter()
+
+// limitRate wraps h so that it answers the requests of a client beyond
+// its rate with 429 Too Many Requests. The rate is that of the API key of
+// the request or, failing that, -rate-limit, per key or per address.
+// Behind a reverse proxy, all requests without a key come from the
+// address of the proxy, and share one limit.
+func limitRate(h http.Handler) http.Handler {
+	def, _ := parseRate(*rateLimit) // checked by setup
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lim, client := def, "addr "+r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			client = "addr " + host
+		}
+		if k := requestKey(r); k != nil {
+			client = "key " + k.Name
+			if k.rate.n > 0 {
+				lim = k.rate
+			}
+		}
+		if lim.n > 0 {
+			if ok, wait := clients.allow(client, lim); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// A limiter limits the rate of requests of each client, by name, with a
+// token bucket: a client may send up to n requests at once, and then one
+// every per/n.
+type limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when it is full again, and can be dropped
+}
+
+func newLimiter() *limiter {
+	return &limiter{buckets: make(map[string]*tokenBucket), swept: time.Now()}
+}
+
+// allow reports whether the client name may send a request now at rate r
+// and, if not, how long it has to wait.
+func (l *limiter) allow(name string, r rate) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.swept) > time.Minute {
+		maps.DeleteFunc(l.buckets, func(_ string, b *tokenBucket) bool { return now.After(b.full) })
+		l.swept = now
+	}
+	b := l.buckets[name]
+	if b == nil {
+		b = &tokenBucket{tokens: float64(r.n), last: now}
+		l.buckets[name] = b
+	}
+	perToken := r.per / time.Duration(r.n)
+	b.tokens = min(float64(r.n), b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration(math.Ceil((1 - b.tokens) * float64(perToken)))
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(r.n) - b.tokens) * float64(perToken)))
+	return true, 0
+}
@@ -0,0 +1,198 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// instapaper saves stories to Instapaper with its simple API
+// (https://www.instapaper.com/api/simple). The simple API has no tags.
+type instapaper struct {
+	username, password string
+}
+
+func newInstapaper(c *config) (sink, error) {
+	s := &instapaper{username: c.Instapaper.Username, password: c.Instapaper.Password}
+	if s.username == "" {
+		s.username, s.password = os.Getenv("INSTAPAPER_USERNAME"), os.Getenv("INSTAPAPER_PASSWORD")
+	}
+	if s.username == "" {
+		return nil, errors.New("no account: set instapaper.username in the config file or $INSTAPAPER_USERNAME")
+	}
+	return s, nil
+}
+
+func (s *instapaper) save(it *item, tags []string) error {
+	form := url.Values{
+		"url":       {storyURL(it)},
+		"title":     {it.PlainTitle()},
+		"selection": {"HN discussion: " + itemURL(it.ID)},
+	}
+	req, err := http.NewRequest("POST", "https://www.instapaper.com/api/add", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.username, s.password)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusForbidden:
+		return errors.New("invalid username or password")
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
+
+// readwise saves stories to Readwise Reader
+// (https://readwise.io/reader_api).
+type readwise struct {
+	token string
+}
+
+func newReadwise(c *config) (sink, error) {
+	token := c.Readwise.Token
+	if token == "" {
+		token = os.Getenv("READWISE_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("no access token: set readwise.token in the config file or $READWISE_TOKEN")
+	}
+	return &readwise{token: token}, nil
+}
+
+func (s *readwise) save(it *item, tags []string) error {
+	body, err := json.Marshal(map[string]any{
+		"url":            storyURL(it),
+		"title":          it.PlainTitle(),
+		"author":         it.By,
+		"published_date": it.Created().UTC().Format("2006-01-02T15:04:05Z"),
+		"tags":           tags,
+		"location":       "later",
+		"saved_using":    "news",
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", "https://readwise.io/api/v3/save/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Token "+s.token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated: // already saved, saved
+		return nil
+	case http.StatusUnauthorized:
+		return errors.New("invalid access token")
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
+
+// wallabag saves stories to a wallabag instance (https://doc.wallabag.org).
+// It authenticates as the user through the OAuth2 password grant with the
+// API client credentials created in the instance.
+type wallabag struct {
+	base               string
+	clientID, secret   string
+	username, password string
+	token              string
+	expires            time.Time
+}
+
+func newWallabag(c *config) (sink, error) {
+	w := c.Wallabag
+	switch {
+	case w.URL == "":
+		return nil, errors.New("no instance: set wallabag.url in the config file")
+	case w.ClientID == "" || w.ClientSecret == "":
+		return nil, errors.New("no API client: set wallabag.client_id and wallabag.client_secret in the config file")
+	case w.Username == "":
+		return nil, errors.New("no account: set wallabag.username and wallabag.password in the config file")
+	}
+	return &wallabag{
+		base:     strings.TrimSuffix(w.URL, "/"),
+		clientID: w.ClientID,
+		secret:   w.ClientSecret,
+		username: w.Username,
+		password: w.Password,
+	}, nil
+}
+
+// authenticate gets an access token, unless the current one is still good.
+func (s *wallabag) authenticate() error {
+	if s.token != "" && time.Now().Before(s.expires) {
+		return nil
+	}
+	resp, err := http.PostForm(s.base+"/oauth/v2/token", url.Values{
+		"grant_type":    {"password"},
+		"client_id":     {s.clientID},
+		"client_secret": {s.secret},
+		"username":      {s.username},
+		"password":      {s.password},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("authentication failed: %s", resp.Status)
+	}
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return err
+	}
+	s.token = t.AccessToken
+	// Renew a little early rather than have a request fail.
+	s.expires = time.Now().Add(time.Duration(t.ExpiresIn)*time.Second - time.Minute)
+	return nil
+}
+
+func (s *wallabag) save(it *item, tags []string) error {
+	if err := s.authenticate(); err != nil {
+		return err
+	}
+	form := url.Values{
+		"url":   {storyURL(it)},
+		"title": {it.PlainTitle()},
+		"tags":  {strings.Join(tags, ",")},
+	}
+	req, err := http.NewRequest("POST", s.base+"/api/entries.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
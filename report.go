@@ -0,0 +1,195 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+)
+
+// hnURL is the address of the HN web site.
+const hnURL = "https://news.ycombinator.com"
+
+// printHTML writes r to w as a standalone HTML document that needs no
+// external resources, so it can be mailed or published as is.
+func printHTML(w io.Writer, r *searchResult) error {
+	return reportTemplate.Execute(w, r)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"css":        func() template.CSS { return reportCSS[*themeName] },
+	"formatRank": formatRank,
+	"formatTime": formatTime,
+	"frontpage":  func() bool { return *frontPageRank },
+	"hostname":   hostname,
+	"isoTime":    func(t time.Time) string { return t.In(location).Format(time.RFC3339) },
+	"itemURL":    itemURL,
+	"pageSize":   func() int { return *pageSize },
+	"userURL":    func(name string) string { return hnURL + "/user?id=" + name },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Total}} Hacker News stories</title>
+<style>
+body { font: 14px/1.4 Verdana, Geneva, sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 8px; text-align: left; vertical-align: top; }
+td.num { text-align: right; }
+a { text-decoration: none; }
+a:hover { text-decoration: underline; }
+.host { font-size: 85%; }
+.snippet, .summary { font-size: 85%; margin-top: 2px; max-width: 60em; }
+.summary { white-space: pre-line; }
+.label { font-size: 75%; border: 1px solid; border-radius: 3px; padding: 0 3px; }
+th { cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+#filter { margin-bottom: 1em; padding: 4px; width: 20em; }
+#pages { margin-top: 1em; }
+#pages button { margin-right: 4px; }
+{{css}}
+</style>
+</head>
+<body>
+<h1>{{.Total}} Hacker News stories</h1>
+<input id="filter" type="search" placeholder="Filter" aria-label="Filter stories">
+<table id="stories" data-page-size="{{pageSize}}">
+<thead>
+<tr>
+	<th>#</th>
+	<th>points</th>
+	<th>comments</th>
+	<th>author</th>
+	<th>time</th>
+	{{- if frontpage}}
+	<th>rank</th>
+	{{- end}}
+	<th>title</th>
+</tr>
+</thead>
+<tbody>
+{{- range .Items}}
+<tr>
+	<td data-value="{{.ID}}"><a href="{{itemURL .ID}}">{{.ID}}</a></td>
+	<td class="num" data-value="{{.Score}}">{{.Score}}</td>
+	<td class="num" data-value="{{.Descendants}}"><a href="{{itemURL .ID}}">{{.Descendants}}</a></td>
+	<td><a href="{{userURL .By}}">{{.By}}</a>
+		{{- with .Author}} <span class="host" title="karma; joined {{formatTime .Joined}}">({{.Karma}})</span>{{end}}</td>
+	<td data-value="{{.Time}}"><time datetime="{{isoTime .Created}}">{{formatTime .Created}}</time></td>
+	{{- if frontpage}}
+	<td class="num" data-value="{{if .Rank}}{{.Rank}}{{else}}1e9{{end}}">{{formatRank .Rank .RankEstimated}}</td>
+	{{- end}}
+	<td>
+		{{- if .URL}}<a href="{{.URL}}">{{.PlainTitle}}</a> <span class="host">({{hostname .URL}})</span>
+		{{- if .Archive}} <a class="host" href="{{.Archive}}">[archive]</a>{{end}}
+		{{- else}}<a href="{{itemURL .ID}}">{{.PlainTitle}}</a>{{end}}
+		{{- range .Labels}} <span class="label">{{.}}</span>{{end}}
+		{{- if .Snippet}}<div class="snippet">{{.Snippet}}</div>{{end}}
+		{{- if .Summary}}<div class="summary">{{.Summary}}</div>{{end -}}
+	</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+<nav id="pages"></nav>
+<script>
+// Click a column header to sort by it, click again to reverse;
+// type in the box to show only the rows containing the text.
+// Long tables are split into pages of data-page-size rows.
+(() => {
+	const table = document.getElementById("stories");
+	const tbody = table.tBodies[0];
+	const rows = Array.from(tbody.rows);
+	const filter = document.getElementById("filter");
+	const pages = document.getElementById("pages");
+	const size = Number(table.dataset.pageSize) || rows.length || 1;
+	let column = -1, order = 1, page = 0;
+
+	const key = (row) => {
+		const cell = row.cells[column];
+		const v = cell.dataset.value;
+		return v === undefined ? cell.textContent.trim().toLowerCase() : Number(v);
+	};
+	const render = () => {
+		const q = filter.value.trim().toLowerCase();
+		const shown = rows.filter((row) => row.textContent.toLowerCase().includes(q));
+		if (column >= 0) {
+			shown.sort((a, b) => {
+				const x = key(a), y = key(b);
+				return x < y ? -order : x > y ? order : 0;
+			});
+		}
+		const n = Math.max(1, Math.ceil(shown.length / size));
+		page = Math.min(page, n - 1);
+		for (const row of rows) {
+			row.hidden = true;
+		}
+		shown.forEach((row, i) => {
+			row.hidden = Math.floor(i / size) !== page;
+			tbody.appendChild(row);
+		});
+
+		pages.replaceChildren();
+		if (n === 1) {
+			return;
+		}
+		for (let i = 0; i < n; i++) {
+			const b = document.createElement("button");
+			b.textContent = i + 1;
+			b.disabled = i === page;
+			b.addEventListener("click", () => {
+				page = i;
+				render();
+				table.scrollIntoView();
+			});
+			pages.appendChild(b);
+		}
+	};
+
+	Array.from(table.tHead.rows[0].cells).forEach((th, i) => {
+		th.addEventListener("click", () => {
+			order = column === i ? -order : 1;
+			column = i;
+			for (const h of table.tHead.rows[0].cells) {
+				h.classList.remove("asc", "desc");
+			}
+			th.classList.add(order > 0 ? "asc" : "desc");
+			render();
+		});
+	});
+	filter.addEventListener("input", () => {
+		page = 0;
+		render();
+	});
+	render();
+})();
+</script>
+</body>
+</html>
+`))
+
+// reportCSS holds the colors of each theme for the HTML report.
+var reportCSS = map[string]template.CSS{
+	"dark": `body { background: #1e1e1e; color: #ddd; }
+a { color: #8ab4f8; }
+th { border-bottom: 2px solid #ff6600; }
+tbody tr:nth-child(even) { background: #262626; }
+.host, time { color: #999; }`,
+	"light": `body { background: #f6f6ef; color: #000; }
+a { color: #000; }
+th { background: #ff6600; }
+tbody tr:nth-child(even) { background: #eeeee4; }
+.host, time { color: #828282; }`,
+}
+
+// itemURL returns the address of the HN page of the item id, where its
+// comments are.
+func itemURL(id int) string {
+	return hnURL + "/item?id=" + strconv.Itoa(id)
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A responseCache holds the responses of the serve command to GET
+// requests for -cache-ttl, so that a dashboard refreshing every few
+// seconds is served without searching again each time. Like sharedCache,
+// requests for a response being made wait for it rather than making
+// another.
+type responseCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+	swept   time.Time
+}
+
+type cachedResponse struct {
+	done   chan struct{} // closed when the response is made
+	status int
+	header http.Header
+	body   bytes.Buffer
+	stored time.Time
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: make(map[string]*cachedResponse), swept: time.Now()}
+}
+
+// streamed are the paths whose responses go on for as long as the client
+// listens, and cannot be cached.
+var streamed = []string{"/events", "/ws"}
+
+// cacheKey returns the key of the response to r: its path and its query,
+// with the parameters sorted and without the API key, under the name of
+// that key, since keys have searches of their own.
+func cacheKey(r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("key")
+	maps.DeleteFunc(q, func(_ string, v []string) bool { return slices.Equal(v, []string{""}) })
+	var name string
+	if k := requestKey(r); k != nil {
+		name = k.Name
+	}
+	return fmt.Sprintf("%s %s?%s", name, r.URL.Path, q.Encode())
+}
+
+// wrap returns a handler serving the GET requests with the cached
+// responses of h, if any.
+func (c *responseCache) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || slices.Contains(streamed, r.URL.Path) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		key := cacheKey(r)
+		c.mu.Lock()
+		if time.Since(c.swept) > c.ttl {
+			c.sweep()
+		}
+		e, ok := c.entries[key]
+		if ok {
+			select {
+			case <-e.done:
+				if e.status != http.StatusOK || time.Since(e.stored) > c.ttl {
+					ok = false
+				}
+			default:
+			}
+		}
+		if !ok {
+			e = &cachedResponse{done: make(chan struct{}), header: make(http.Header)}
+			c.entries[key] = e
+			c.mu.Unlock()
+			func() {
+				defer close(e.done)
+				w.Header().Set("X-Cache", "MISS")
+				rec := &responseRecorder{w: w, e: e, before: w.Header().Clone()}
+				h.ServeHTTP(rec, r)
+				e.stored = time.Now()
+				if e.status == 0 {
+					e.status = http.StatusOK
+				}
+			}()
+			return
+		}
+		c.mu.Unlock()
+		<-e.done
+		if e.status != http.StatusOK {
+			// The response being waited for failed; try again.
+			h.ServeHTTP(w, r)
+			return
+		}
+		maps.Copy(w.Header(), e.header)
+		w.Header().Set("X-Cache", "HIT")
+		w.Header().Set("Age", fmt.Sprint(int(time.Since(e.stored).Seconds())))
+		w.WriteHeader(e.status)
+		w.Write(e.body.Bytes())
+	})
+}
+
+// sweep drops the stale entries. The caller holds c.mu.
+func (c *responseCache) sweep() {
+	for k, e := range c.entries {
+		select {
+		case <-e.done:
+			if time.Since(e.stored) > c.ttl {
+				delete(c.entries, k)
+			}
+		default:
+		}
+	}
+	c.swept = time.Now()
+}
+
+// A responseRecorder writes a response to w and keeps a copy in e,
+// without the header fields set before, such as those of allowCORS,
+// which depend on the request.
+type responseRecorder struct {
+	w      http.ResponseWriter
+	e      *cachedResponse
+	before http.Header
+}
+
+func (r *responseRecorder) Header() http.Header { return r.w.Header() }
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.e.status != 0 {
+		return
+	}
+	r.e.status = status
+	for k, v := range r.w.Header() {
+		if !slices.Equal(v, r.before[k]) {
+			r.e.header[k] = slices.Clone(v)
+		}
+	}
+	r.w.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.e.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.e.body.Write(p)
+	return r.w.Write(p)
+}
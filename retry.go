@@ -0,0 +1,122 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// retryBackoff is the time waited before the first retry; it doubles for
+// each retry after that.
+const retryBackoff = 250 * time.Millisecond
+
+// A retryTransport retries GET and HEAD requests that fail with network
+// errors or server errors, up to -max-retries times each, waiting longer
+// after each attempt, or as long as the server asks with Retry-After. The
+// time spent waiting across the run is bounded by -retry-max-elapsed;
+// once spent, or if the server asks for more, failures are returned at
+// once.
+type retryTransport struct {
+	base http.RoundTripper
+
+	mu     sync.Mutex
+	waited time.Duration // in all, across requests
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return t.base.RoundTrip(req)
+	}
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if !retryable(resp, err) || attempt >= *maxRetries {
+			return resp, err
+		}
+		// Jitter keeps the many concurrent fetches from retrying in
+		// lockstep.
+		wait := backoff/2 + rand.N(backoff/2+1)
+		backoff *= 2
+		// The server may say how long to back off, typically when
+		// throttling with 429 Too Many Requests.
+		d, throttled := retryAfter(resp)
+		if throttled {
+			wait = d
+			timings.throttled.Add(1)
+		}
+		if !t.spend(wait) {
+			return resp, err
+		}
+		if throttled && *debug {
+			log.Printf("%s: %s, retrying after %v", req.URL.Host, resp.Status, d)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		timings.retries.Add(1)
+		countRetry(req)
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// retrier is the retrying transport set up by setupTransport.
+var retrier *retryTransport
+
+// reset renews the retry budget, for the serve command, which runs the
+// searches of one cycle after another.
+func (t *retryTransport) reset() {
+	t.mu.Lock()
+	t.waited = 0
+	t.mu.Unlock()
+}
+
+// spend takes d from the retry budget of the run, and reports whether
+// there was enough left.
+func (t *retryTransport) spend(d time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if *retryMaxElapsed > 0 && t.waited+d > *retryMaxElapsed {
+		return false
+	}
+	t.waited += d
+	return true
+}
+
+// retryAfter returns the time to wait that the Retry-After header of resp
+// asks for, either as seconds or as a date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	h := resp.Header.Get("Retry-After")
+	if h == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(h); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// retryable reports whether a request that ended with resp and err may
+// succeed if tried again.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Comments    string   `xml:"comments"`
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	Categories  []string `xml:"category"`
+}
+
+// writeRSS writes items to w as an RSS 2.0 feed with the given title
+// and description. The link of each entry is that of the story, and its
+// comments link is the discussion on Hacker News.
+func writeRSS(w io.Writer, title, description string, items []item) error {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        hnURL,
+			Description: description,
+		},
+	}
+	for i := range items {
+		it := &items[i]
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       it.PlainTitle(),
+			Link:        storyURL(it),
+			Comments:    itemURL(it.ID),
+			GUID:        itemURL(it.ID),
+			PubDate:     it.Created().In(location).Format(time.RFC1123Z),
+			Creator:     it.By,
+			Description: it.Text,
+			Categories:  it.Labels,
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,344 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// runServe implements the serve command, which serves over HTTP:
+//
+//   - /, a web UI to search with, whose results are kept up to date
+//     through /events (see serveEvents), or /ws for WebSocket clients
+//     (see serveWebSocket);
+//   - /feed/NAME, an RSS feed of the matches of the saved search NAME;
+//   - /searches.opml, an OPML list of those feeds, to import them all
+//     into a feed reader at once;
+//   - /trigger, to run a search on demand (see serveTrigger);
+//   - /graphql, a GraphQL API (see serveGraphQL);
+//   - /threads.ics, a calendar of the monthly threads, such as Who is
+//     hiring? (see serveThreads);
+//   - /openapi.json, an OpenAPI document describing all of these;
+//   - the feeds of hnrss.org (see addHNRSSRoutes).
+//
+// Meanwhile, it runs the saved searches with a schedule (see
+// runScheduled) and the watchlist (see runWatchlist), sends the digests
+// that are due and retries saving the stories that failed to be saved.
+// All of these share the items fetched within a minute (see sharedTTL).
+//
+// It reuses its responses to GET requests for -cache-ttl; see
+// responseCache. With API keys in the configuration file, it requires one in every
+// request; see authenticate. Clients sending too many requests are
+// turned away; see limitRate. With -cors-origins, browsers let the pages
+// of those origins call it; see allowCORS.
+//
+// On SIGTERM or an interrupt, it shuts down gracefully; see shutdown. On
+// SIGHUP, it reloads the configuration file and restarts the scheduled
+// searches and the watchlist with it, remembering what they have already
+// saved. Changes to the TLS settings take a restart.
+// When run by systemd, it supports socket activation, readiness
+// notification and the watchdog.
+func runServe() {
+	shared = newSharedCache()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feed/{name}", serveFeed)
+	mux.HandleFunc("GET /searches.opml", serveOPML)
+	mux.HandleFunc("/graphql", serveGraphQL)
+	mux.HandleFunc("POST /trigger", serveTrigger)
+	mux.HandleFunc("GET /openapi.json", serveOpenAPI)
+	mux.HandleFunc("GET /threads.ics", serveThreads)
+	addHNRSSRoutes(mux)
+	addUIRoutes(mux)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var jobs sync.WaitGroup
+	seen := make(seenSets)
+	jobsCtx, cancelJobs := context.WithCancel(ctx)
+	startJobs(jobsCtx, &jobs, seen)
+
+	l, err := activationListener()
+	if err != nil {
+		fatal(err)
+	}
+	if l == nil {
+		if l, err = net.Listen("tcp", *listenAddr); err != nil {
+			fatal(err)
+		}
+	}
+	log.Printf("listening on %s", l.Addr())
+	if err := sdNotify("READY=1"); err != nil {
+		log.Printf("sd_notify: %v", err)
+	}
+	go watchdog()
+	var h http.Handler = mux
+	if *cacheTTL > 0 {
+		h = newResponseCache(*cacheTTL).wrap(h)
+	}
+	// The contexts of the requests are canceled on shutdown, to end the
+	// streams of events, which would otherwise hold it up.
+	base, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	srv := &http.Server{
+		Handler:     allowCORS(authenticate(limitRate(h))),
+		BaseContext: func(net.Listener) context.Context { return base },
+	}
+	srv.RegisterOnShutdown(cancelBase)
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(l) }()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for {
+		select {
+		case err := <-errc:
+			fatal(err)
+		case <-hup:
+			c, err := loadConfig(*configFile, true)
+			if err != nil {
+				log.Printf("reload: %v; keeping the current configuration", err)
+				continue
+			}
+			sdNotify("RELOADING=1")
+			cancelJobs()
+			jobs.Wait()
+			current.Store(c)
+			jobsCtx, cancelJobs = context.WithCancel(ctx)
+			startJobs(jobsCtx, &jobs, seen)
+			log.Printf("reloaded %s", *configFile)
+			sdNotify("READY=1")
+		case <-ctx.Done():
+			cancelJobs()
+			shutdown(srv, &jobs)
+			return
+		}
+	}
+}
+
+// startJobs starts, in jobs, the scheduled searches and the watchlist of
+// the current configuration, and the periodic delivery of digests and
+// queued matches. They run until ctx is done; seen is kept by the caller
+// across restarts.
+func startJobs(ctx context.Context, jobs *sync.WaitGroup, seen seenSets) {
+	c := cfg()
+	// The sets are looked up here, as seen is not safe for concurrent use;
+	// each job then owns its own.
+	for _, s := range c.Searches {
+		if sched, _ := s.schedule(); sched != nil {
+			set := seen.get("search/" + s.Name)
+			jobs.Go(func() { runScheduled(ctx, s, sched, set) })
+		}
+	}
+	for _, k := range c.Keys {
+		for _, s := range k.Searches {
+			if sched, _ := s.schedule(); sched != nil {
+				set := seen.get("search/" + k.Name + "/" + s.Name)
+				jobs.Go(func() { runScheduled(ctx, s, sched, set) })
+			}
+		}
+	}
+	if len(c.Watchlist.Entries) > 0 {
+		sets := make([]map[int]bool, len(c.Watchlist.Entries))
+		for i, e := range c.Watchlist.Entries {
+			sets[i] = seen.get("watchlist/" + e.Name)
+		}
+		jobs.Go(func() { runWatchlist(ctx, c.Watchlist, sets) })
+	}
+	jobs.Go(func() {
+		tick := time.NewTicker(time.Minute)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				retrier.reset()
+				deliverPending()
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// shutdown stops srv from accepting requests and waits, for up to
+// -drain-timeout, for the requests under way and the jobs to finish. Then
+// it delivers what is pending, so that it is not held until the next
+// start.
+func shutdown(srv *http.Server, jobs *sync.WaitGroup) {
+	log.Print("shutting down")
+	sdNotify("STOPPING=1")
+	ctx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Print("shutdown: searches still running; giving up on them")
+	}
+	deliverPending()
+}
+
+// runSearch runs the saved search s.
+func runSearch(s savedSearch) (*searchResult, error) {
+	re, err := compile(s.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return search(s.List, re)
+}
+
+func serveFeed(w http.ResponseWriter, r *http.Request) {
+	s, err := requestSearch(r, r.PathValue("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	result, err := runSearch(s)
+	if err != nil {
+		log.Printf("%s: %v", s.Name, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	serveRSS(w, "news: "+s.Name, "Hacker News stories matching "+s.Pattern, result.Items)
+}
+
+// maxRequestBody bounds the bodies of the requests to /trigger and
+// /graphql.
+const maxRequestBody = 1 << 20
+
+// serveTrigger runs the search in the body of the request, a JSON object
+// with either the name of a saved search or a pattern and list, such as
+//
+//	{"pattern": "(?i)rust", "list": "top"}
+//
+// and replies with the matches as JSON. With "save_to", the matches are
+// also saved to those sinks, in addition to those of the saved search.
+func serveTrigger(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name    string   `json:"name"`
+		Pattern string   `json:"pattern"`
+		List    string   `json:"list"`
+		SaveTo  []string `json:"save_to"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s := savedSearch{Pattern: req.Pattern, List: req.List}
+	if req.Name != "" {
+		var err error
+		if s, err = requestSearch(r, req.Name); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+	if s.Pattern == "" {
+		http.Error(w, "no pattern or name", http.StatusBadRequest)
+		return
+	}
+	if _, err := compile(s.Pattern); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.List == "" {
+		s.List = "new"
+	}
+	s.SaveTo = append(s.SaveTo, req.SaveTo...)
+	result, err := runSearch(s)
+	if err != nil {
+		log.Printf("trigger: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if len(s.SaveTo) > 0 {
+		if err := saveMatches(s.SaveTo, s.Name, result); err != nil {
+			log.Printf("trigger: %v", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+type opml struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Outline []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Type   string `xml:"type,attr"`
+	Text   string `xml:"text,attr"`
+	Title  string `xml:"title,attr"`
+	XMLURL string `xml:"xmlUrl,attr"`
+}
+
+func serveOPML(w http.ResponseWriter, r *http.Request) {
+	doc := opml{Version: "2.0", Title: "news saved searches"}
+	base := baseURL(r)
+	var query string
+	if k := requestKey(r); k != nil {
+		// Feed readers cannot send the key otherwise.
+		query = "?key=" + url.QueryEscape(k.Key)
+	}
+	for _, s := range searches(r) {
+		doc.Outline = append(doc.Outline, opmlOutline{
+			Type:   "rss",
+			Text:   s.Name,
+			Title:  s.Name,
+			XMLURL: base + "/feed/" + url.PathEscape(s.Name) + query,
+		})
+	}
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	if err := writeOPML(w, &doc); err != nil {
+		log.Print(err)
+	}
+}
+
+func writeOPML(w io.Writer, doc *opml) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// baseURL returns the scheme and host the request r was sent to, for
+// the absolute links feed readers need.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if p := r.Header.Get("X-Forwarded-Proto"); p != "" {
+		scheme = p
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,223 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// A sink is a service that matched stories can be saved to, such as a
+// bookmarking or read-later service, or a message broker.
+type sink interface {
+	// save saves the story it, labeled with tags.
+	save(it *item, tags []string) error
+}
+
+// sinks maps sink names to their constructors.
+var sinks = map[string]func(*config) (sink, error){
+	"email":      newEmail,
+	"instapaper": newInstapaper,
+	"kafka":      newKafka,
+	"mqtt":       newMQTT,
+	"nats":       newNATS,
+	"pinboard":   newPinboard,
+	"readwise":   newReadwise,
+	"wallabag":   newWallabag,
+}
+
+// saveMatches saves the items of r to each of the named sinks, tagged
+// with "hn", the name of the search, if any, and the labels of the item.
+// Stories saved to a sink within its dedup window are skipped, and those
+// for sinks in digest mode are queued instead; see queueDigest. Every
+// sink and item is tried even if some fail; the errors are returned
+// joined, and the failed items are queued to be tried again by later
+// calls; see retryQueued. stateMu is held only to read and write the
+// state files, not while saving to the sinks.
+func saveMatches(names []string, search string, r *searchResult) error {
+	tags := []string{"hn"}
+	if t := searchTag(search); t != "" {
+		tags = append(tags, t)
+	}
+	if err := retryQueued(); err != nil {
+		log.Printf("queue: %v", err)
+	}
+	var state notifiedState
+	for _, name := range names {
+		if dedupWindow(name) > 0 {
+			var err error
+			stateMu.Lock()
+			state, err = loadNotified(notifiedFile())
+			stateMu.Unlock()
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	var errs []error
+	var failed []queued
+	saved := make(notifiedState) // to add to the state
+	for _, name := range names {
+		newSink, ok := sinks[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown sink %q: want one of %s", name, sinkNames()))
+			continue
+		}
+		s, err := newSink(cfg())
+		if err != nil {
+			err = fmt.Errorf("%s: %v", name, err)
+			log.Print(err)
+			errs = append(errs, err)
+			continue
+		}
+		window := dedupWindow(name)
+		if digestInterval(name) > 0 {
+			var fresh []item
+			for _, it := range r.Items {
+				if window == 0 || !state.recent(name, it.ID, window) {
+					fresh = append(fresh, it)
+				}
+			}
+			if err := queueDigest(name, s, tags, fresh); err != nil {
+				err = fmt.Errorf("%s: %v", name, err)
+				log.Print(err)
+				errs = append(errs, err)
+			} else if window > 0 {
+				for _, it := range fresh {
+					saved.add(name, it.ID)
+				}
+			}
+			continue
+		}
+		for i := range r.Items {
+			if window > 0 && state.recent(name, r.Items[i].ID, window) {
+				continue
+			}
+			tags := slices.Clip(tags)
+			for _, l := range r.Items[i].Labels {
+				if t := searchTag(l); t != "" && !slices.Contains(tags, t) {
+					tags = append(tags, t)
+				}
+			}
+			if err := s.save(&r.Items[i], tags); err != nil {
+				e := queued{Sink: name, Tags: tags, Item: r.Items[i]}
+				if e.fail(err) {
+					failed = append(failed, e)
+					err = fmt.Errorf("%s: saving %d: %v; queued to try again later", name, r.Items[i].ID, err)
+				} else {
+					deadLetter(e)
+					err = fmt.Errorf("%s: saving %d: %v", name, r.Items[i].ID, err)
+				}
+				log.Print(err)
+				errs = append(errs, err)
+			} else if window > 0 {
+				saved.add(name, r.Items[i].ID)
+			}
+		}
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if len(saved) > 0 {
+		// Reload the state, which other searches may have changed while
+		// saving.
+		state, err := loadNotified(notifiedFile())
+		if err == nil {
+			for name, ids := range saved {
+				for id, t := range ids {
+					if state[name] == nil {
+						state[name] = make(map[int]int64)
+					}
+					state[name][id] = t
+				}
+			}
+			err = state.save(notifiedFile(), names)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(failed) > 0 {
+		if err := enqueue(failed); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// A batchSink is a sink that can also save several stories at once, as
+// a single message, for digests.
+type batchSink interface {
+	sink
+	// saveBatch saves the stories items, labeled with tags.
+	saveBatch(items []item, tags []string) error
+}
+
+// sinkNames returns the names of the sinks known, for messages.
+func sinkNames() string {
+	var names []string
+	for name := range sinks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+var nonTag = regexp.MustCompile(`[^\pL\pN_.-]+`)
+
+// searchTag turns the name of a search into a tag: lower case, without
+// spaces or commas.
+func searchTag(name string) string {
+	return strings.Trim(nonTag.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// storyURL returns the address a story links to, or its HN page if it has
+// no link, as with Ask HN.
+func storyURL(it *item) string {
+	if it.URL != "" {
+		return it.URL
+	}
+	return itemURL(it.ID)
+}
+
+// topicData is the data the topic templates of message sinks are
+// executed with. Besides the fields of the story, .Search is the tag of
+// the search, or "hn" if it has no name. For digests, which hold several
+// stories, the fields of the story are zero.
+type topicData struct {
+	*item
+	Search string
+	Tags   []string
+}
+
+// topicName executes the topic template t for the story it, saved with
+// tags.
+func topicName(t *template.Template, it *item, tags []string) (string, error) {
+	data := topicData{item: it, Search: "hn", Tags: tags}
+	if len(tags) > 1 {
+		data.Search = tags[1]
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// hostPort returns the address to dial for the server u, with port if u
+// has none.
+func hostPort(u *url.URL, port string) string {
+	if p := u.Port(); p != "" {
+		port = p
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
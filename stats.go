@@ -0,0 +1,208 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// runStats implements the stats command. It prints aggregate figures
+// about the stories of the selected list, or about those that match the
+// pattern in args, if any.
+func runStats(args []string) {
+	var re *pattern
+	if len(args) > 0 {
+		var err error
+		if re, err = compile(args[0]); err != nil {
+			fatal(err)
+		}
+	}
+	r, err := search(listName(), re)
+	if err != nil {
+		fatal(err)
+	}
+	if err := printStats(os.Stdout, computeStats(r.Items)); err != nil {
+		fatal(err)
+	}
+}
+
+type stats struct {
+	Stories       int
+	Score         summary // of the points of the stories
+	Comments      summary // of the comment counts of the stories
+	Histogram     []bucket
+	TopDomains    []count
+	TopAuthors    []count
+	NoURL         int // stories without a URL, such as Ask HN
+	MostDiscussed *item
+}
+
+// A summary describes the distribution of a set of values.
+type summary struct {
+	Min, Median, P90, Max int
+	Mean                  float64
+}
+
+// A bucket counts the stories whose score lies in [Low, High).
+// High is 0 for the last, unbounded bucket.
+type bucket struct {
+	Low, High int
+	N         int
+}
+
+type count struct {
+	Name string
+	N    int
+}
+
+// scoreBuckets are the bounds of the score histogram.
+var scoreBuckets = []int{0, 10, 50, 100, 250, 500}
+
+func computeStats(items []item) *stats {
+	st := &stats{Stories: len(items)}
+	var scores, comments []int
+	domains := make(map[string]int)
+	authors := make(map[string]int)
+	for i := range items {
+		it := &items[i]
+		scores = append(scores, it.Score)
+		comments = append(comments, it.Descendants)
+		if host := hostname(it.URL); host != "" {
+			domains[host]++
+		} else {
+			st.NoURL++
+		}
+		if it.By != "" {
+			authors[it.By]++
+		}
+		if st.MostDiscussed == nil || it.Descendants > st.MostDiscussed.Descendants {
+			st.MostDiscussed = it
+		}
+	}
+	st.Score = summarize(scores)
+	st.Comments = summarize(comments)
+	for i, low := range scoreBuckets {
+		b := bucket{Low: low}
+		if i+1 < len(scoreBuckets) {
+			b.High = scoreBuckets[i+1]
+		}
+		for _, s := range scores {
+			if s >= b.Low && (b.High == 0 || s < b.High) {
+				b.N++
+			}
+		}
+		st.Histogram = append(st.Histogram, b)
+	}
+	st.TopDomains = mostCommon(domains, *topCount)
+	st.TopAuthors = mostCommon(authors, *topCount)
+	return st
+}
+
+// summarize returns the summary of values.
+func summarize(values []int) summary {
+	if len(values) == 0 {
+		return summary{}
+	}
+	v := slices.Clone(values)
+	slices.Sort(v)
+	sum := 0
+	for _, x := range v {
+		sum += x
+	}
+	return summary{
+		Min:    v[0],
+		Median: v[len(v)/2],
+		P90:    v[len(v)*9/10],
+		Max:    v[len(v)-1],
+		Mean:   float64(sum) / float64(len(v)),
+	}
+}
+
+// mostCommon returns the n names with the highest counts in m, most frequent
+// first and alphabetically among ties.
+func mostCommon(m map[string]int, n int) []count {
+	var counts []count
+	for name, c := range m {
+		counts = append(counts, count{name, c})
+	}
+	slices.SortFunc(counts, func(a, b count) int {
+		if c := cmp.Compare(b.N, a.N); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+func printStats(w io.Writer, st *stats) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "stories   %d (%d without a link)\n", st.Stories, st.NoURL)
+	if st.Stories == 0 {
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	fmt.Fprintf(&b, "points    min %d, median %d, mean %.1f, p90 %d, max %d\n",
+		st.Score.Min, st.Score.Median, st.Score.Mean, st.Score.P90, st.Score.Max)
+	fmt.Fprintf(&b, "comments  min %d, median %d, mean %.1f, p90 %d, max %d\n",
+		st.Comments.Min, st.Comments.Median, st.Comments.Mean, st.Comments.P90, st.Comments.Max)
+	if it := st.MostDiscussed; it != nil && it.Descendants > 0 {
+		fmt.Fprintf(&b, "          most discussed: %s (%d comments)\n", it.PlainTitle(), it.Descendants)
+	}
+
+	counts := make([]int, len(st.Histogram))
+	for i, bk := range st.Histogram {
+		counts[i] = bk.N
+	}
+	fmt.Fprintf(&b, "\npoints distribution  %s\n", sparkline(counts))
+	most := 0
+	for _, bk := range st.Histogram {
+		most = max(most, bk.N)
+	}
+	for _, bk := range st.Histogram {
+		label := fmt.Sprintf("%d-%d", bk.Low, bk.High-1)
+		if bk.High == 0 {
+			label = fmt.Sprintf("%d+", bk.Low)
+		}
+		bar := 0
+		if most > 0 {
+			bar = (bk.N*histogramWidth + most - 1) / most
+		}
+		line := fmt.Sprintf("  %-8s %5d  %s", label, bk.N, strings.Repeat("#", bar))
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+
+	writeCounts(&b, "top domains", st.TopDomains)
+	writeCounts(&b, "top authors", st.TopAuthors)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeCounts writes a ranking under the given title, preceded by a blank
+// line. Nothing is written for an empty ranking.
+func writeCounts(b *strings.Builder, title string, counts []count) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s\n", title)
+	width := 0
+	for _, c := range counts {
+		width = max(width, utf8.RuneCountInString(c.Name))
+	}
+	for _, c := range counts {
+		fmt.Fprintf(b, "  %s%s %5d\n", c.Name, strings.Repeat(" ", width-utf8.RuneCountInString(c.Name)), c.N)
+	}
+}
+
+// histogramWidth is the length of the longest bar in a histogram.
+const histogramWidth = 40
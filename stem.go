@@ -0,0 +1,85 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A stemPhrase matches the text that contains its words, in order and
+// next to each other, once the words of both are reduced to their stems.
+type stemPhrase struct {
+	source string
+	stems  []string
+}
+
+func newStemPhrase(s string) (*stemPhrase, error) {
+	stems := stemWords(s)
+	if len(stems) == 0 {
+		return nil, fmt.Errorf("-stem: no words in %q", s)
+	}
+	return &stemPhrase{s, stems}, nil
+}
+
+func (p *stemPhrase) match(s string) bool {
+	words := stemWords(s)
+	for i := 0; i+len(p.stems) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(p.stems)], p.stems) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *stemPhrase) String() string {
+	return "stem:" + p.source
+}
+
+// stemWords returns the stems of the words, runs of letters and digits,
+// of s.
+func stemWords(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = stemWord(w)
+	}
+	return words
+}
+
+// suffixes are the English inflectional and derivational endings that
+// stemWord removes, longest first, with what replaces them.
+var suffixes = []struct{ suffix, repl string }{
+	{"izations", "ize"}, {"ization", "ize"}, {"ations", "ate"}, {"ation", "ate"},
+	{"ments", ""}, {"ment", ""}, {"nesses", ""}, {"ness", ""},
+	{"ings", ""}, {"ing", ""}, {"ies", "y"}, {"ied", "y"},
+	{"ers", ""}, {"er", ""}, {"ed", ""}, {"ly", ""}, {"s", ""},
+}
+
+// stemWord reduces the lower-case word w to its stem by removing a
+// suffix, so that deploys, deployed, deploying and deployment all become
+// deploy. It is a light stemmer for English, cruder than Porter's, but it
+// only needs to be consistent: titles and patterns go through the same.
+// Stems are kept at least three letters long.
+func stemWord(w string) string {
+	for _, s := range suffixes {
+		stem, ok := strings.CutSuffix(w, s.suffix)
+		if !ok || utf8.RuneCountInString(stem) < 3 || s.suffix == "s" && strings.HasSuffix(stem, "s") {
+			continue // leave class alone
+		}
+		w = stem + s.repl
+		break
+	}
+	// Undo the doubled consonants of running and planned, and drop the
+	// final e of release and releases, so they meet releas(ed).
+	if n := len(w); n > 3 && w[n-1] == w[n-2] && !strings.ContainsRune("aeiouylsz", rune(w[n-1])) {
+		w = w[:n-1]
+	}
+	return strings.TrimSuffix(w, "e")
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// An emitter passes the matches of a search on to a function as they are
+// found, optionally in the order of the story list.
+type emitter struct {
+	emit    func(*item)
+	ordered bool
+	index   map[int]int // position of each story in the list
+	done    []bool      // whether the story at each position was fetched
+	pending []*item     // matches fetched before their turn
+	next    int         // position of the next story to emit
+}
+
+func newEmitter(stories []int, emit func(*item), ordered bool) *emitter {
+	e := &emitter{emit: emit, ordered: ordered}
+	if emit != nil && ordered {
+		e.index = make(map[int]int, len(stories))
+		for i, id := range stories {
+			e.index[id] = i
+		}
+		e.done = make([]bool, len(stories))
+		e.pending = make([]*item, len(stories))
+	}
+	return e
+}
+
+// add tells e that the story it was fetched, and whether it matched.
+func (e *emitter) add(it *item, matched bool) {
+	if e.emit == nil {
+		return
+	}
+	if !e.ordered {
+		if matched {
+			e.emit(it)
+		}
+		return
+	}
+	i, ok := e.index[it.ID]
+	if !ok {
+		return
+	}
+	e.done[i] = true
+	if matched {
+		e.pending[i] = it
+	}
+	for e.next < len(e.done) && e.done[e.next] {
+		if p := e.pending[e.next]; p != nil {
+			e.emit(p)
+			e.pending[e.next] = nil
+		}
+		e.next++
+	}
+}
+
+// streamItem writes it to w as a line of tab-separated fields: ID, points,
+// comments, author, time, title and URL.
+func streamItem(w io.Writer, it *item) {
+	fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%s\t%s\t%s\n", it.ID, it.Score, it.Descendants,
+		it.By, it.Created().In(location).Format(time.RFC3339), it.PlainTitle(), storyURL(it))
+}
+
+// streamMatches writes the parts of it that re matches to w, each as a
+// line of tab-separated fields: ID, URL and the match.
+func streamMatches(w io.Writer, it *item, re *pattern) {
+	for _, m := range re.findAll(it) {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", it.ID, storyURL(it), strings.ReplaceAll(m, "\n", " "))
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// summarizeTimeout bounds the time the summarizer may take per story.
+const summarizeTimeout = 5 * time.Minute
+
+// addSummaries sets the Summary field of each item of r to the output of the
+// shell command cmd, run with the text of the story's article on its
+// standard input. Articles not fetched yet are fetched first; for stories
+// without a link, their own text is used. The title, URL and ID of the
+// story are passed in the environment as NEWS_TITLE, NEWS_URL and
+// NEWS_ID. Failures are logged and leave the summary empty.
+func addSummaries(r *searchResult, cmd string) {
+	for i := range r.Items {
+		it := &r.Items[i]
+		text := it.Content
+		switch {
+		case text != "":
+		case it.URL != "":
+			var err error
+			if text, err = fetchContent(it.URL, *contentMaxBytes); err != nil {
+				noteError("summarize", it.ID, err)
+				continue
+			}
+		default:
+			text = it.PlainText()
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		summary, err := runSummarizer(cmd, text, it)
+		if err != nil {
+			noteError("summarize", it.ID, err)
+			continue
+		}
+		it.Summary = summary
+	}
+}
+
+func runSummarizer(cmd, text string, it *item) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), summarizeTimeout)
+	defer cancel()
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(ctx, "cmd", "/C", cmd)
+	} else {
+		c = exec.CommandContext(ctx, "sh", "-c", cmd)
+	}
+	c.Stdin = strings.NewReader(text)
+	c.Env = append(os.Environ(),
+		"NEWS_TITLE="+it.PlainTitle(),
+		"NEWS_URL="+storyURL(it),
+		"NEWS_ID="+strconv.Itoa(it.ID),
+	)
+	var stdout, stderr bytes.Buffer
+	c.Stdout, c.Stderr = &stdout, &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
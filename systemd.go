@@ -0,0 +1,73 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// The serve command integrates with systemd when run as a service: it
+// listens on the socket passed by socket activation, if any, and reports
+// its state to the service manager, including watchdog keep-alive pings.
+// See sd_listen_fds(3) and sd_notify(3).
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+// activationListener returns the first socket passed by systemd socket
+// activation, or nil if the process was not socket-activated.
+func activationListener() (net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	f := os.NewFile(listenFDsStart, "LISTEN_FD_3")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// sdNotify sends state, such as "READY=1", to the service manager. It
+// does nothing when not run by systemd.
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' { // abstract namespace
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdog pings the service manager at half the watchdog interval, if
+// the service has one, for as long as the process runs.
+func watchdog() {
+	usec, err := strconv.Atoi(os.Getenv("WATCHDOG_USEC"))
+	if err != nil || usec <= 0 {
+		return
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return
+	}
+	for range time.Tick(time.Duration(usec) * time.Microsecond / 2) {
+		sdNotify("WATCHDOG=1")
+	}
+}
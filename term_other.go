@@ -0,0 +1,14 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !(darwin || freebsd || linux || netbsd || openbsd)
+
+package main
+
+import "os"
+
+// terminalWidth returns 0: terminal sizes are not detected on this system.
+func terminalWidth(f *os.File) int {
+	return 0
+}
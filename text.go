@@ -0,0 +1,150 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	paragraphTag = regexp.MustCompile(`(?i)<p\s*/?>`)
+	anyTag       = regexp.MustCompile(`<[^>]*>`)
+	tagName      = regexp.MustCompile(`^<(/?)([a-zA-Z]+)`)
+	hrefAttr     = regexp.MustCompile(`href="([^"]*)"`)
+)
+
+// stripTags removes the markup from the HTML fragment s and decodes its
+// entities. Paragraph breaks become newlines.
+func stripTags(s string) string {
+	s = paragraphTag.ReplaceAllString(s, "\n")
+	s = anyTag.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
+
+// PlainTitle returns the title of the item with its HTML entities decoded.
+func (it *item) PlainTitle() string {
+	return html.UnescapeString(it.Title)
+}
+
+// PlainText returns the text of the item as plain text.
+func (it *item) PlainText() string {
+	return stripTags(it.Text)
+}
+
+// matches reports whether p matches the decoded title or text of the
+// item, after normalizing them as the pattern was (see normalize), or
+// the parts of its URL p is scoped to.
+func (it *item) matches(p *pattern) bool {
+	return p.matchText(normalize(it.PlainTitle(), *fold)) ||
+		p.matchText(normalize(it.PlainText(), *fold)) ||
+		p.matchesURL(it)
+}
+
+// htmlToText converts the HTML subset used in HN texts (<p>, <a>, <i>,
+// <pre> and <code>, plus entities) into readable plain text. Paragraphs are
+// wrapped at width; preformatted blocks are indented and left as is.
+func htmlToText(s string, width int) string {
+	var (
+		blocks    []string
+		b         strings.Builder
+		pre       bool
+		href      string
+		linkStart int
+	)
+	flush := func() {
+		text := b.String()
+		b.Reset()
+		if pre {
+			text = strings.Trim(text, "\n")
+			if text != "" {
+				blocks = append(blocks, "    "+strings.ReplaceAll(text, "\n", "\n    "))
+			}
+			return
+		}
+		if text = wrap(text, width); text != "" {
+			blocks = append(blocks, text)
+		}
+	}
+	for s != "" {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			i = len(s)
+		}
+		b.WriteString(html.UnescapeString(s[:i]))
+		s = s[i:]
+		if s == "" {
+			break
+		}
+		tag := anyTag.FindString(s)
+		if tag == "" {
+			b.WriteString(s)
+			break
+		}
+		s = s[len(tag):]
+		m := tagName.FindStringSubmatch(tag)
+		if m == nil {
+			continue
+		}
+		closing := m[1] == "/"
+		switch strings.ToLower(m[2]) {
+		case "p":
+			flush()
+		case "pre":
+			flush()
+			pre = !closing
+		case "i", "em":
+			b.WriteString("*")
+		case "a":
+			if !closing {
+				href, linkStart = "", b.Len()
+				if m := hrefAttr.FindStringSubmatch(tag); m != nil {
+					href = html.UnescapeString(m[1])
+				}
+				break
+			}
+			if href == "" {
+				break
+			}
+			// HN shortens the text of long links with "...", so prefer
+			// the full target in that case.
+			text := b.String()
+			link := text[linkStart:]
+			if strings.HasPrefix(href, strings.TrimSuffix(link, "...")) {
+				b.Reset()
+				b.WriteString(text[:linkStart] + href)
+			} else if link != href {
+				b.WriteString(" (" + href + ")")
+			}
+			href = ""
+		}
+	}
+	flush()
+	return strings.Join(blocks, "\n\n")
+}
+
+// wrap fills the words of s into lines of at most width runes.
+// Words longer than width are put on a line of their own.
+func wrap(s string, width int) string {
+	var b strings.Builder
+	n := 0
+	for _, word := range strings.Fields(s) {
+		w := utf8.RuneCountInString(word)
+		switch {
+		case n == 0:
+		case n+1+w > width:
+			b.WriteByte('\n')
+			n = 0
+		default:
+			b.WriteByte(' ')
+			n++
+		}
+		b.WriteString(word)
+		n += w
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The monthly threads of Hacker News are posted by the whoishiring
+// account on the first weekday of each month, at 11 AM in New York.
+const (
+	threadsUser = "whoishiring"
+	threadsHour = 11
+)
+
+// threadTitle matches the titles of the monthly threads, such as
+// "Ask HN: Who is hiring? (October 2026)".
+var threadTitle = regexp.MustCompile(`^Ask HN: (Who is hiring\?|Who wants to be hired\?|Freelancer\? Seeking freelancer\?) \(([A-Z][a-z]+) (\d{4})\)$`)
+
+// threadKinds are the monthly threads, by the question in their title.
+var threadKinds = []string{"Who is hiring?", "Who wants to be hired?", "Freelancer? Seeking freelancer?"}
+
+// A thread is a monthly thread, whether it was posted yet or not.
+type thread struct {
+	kind  string
+	month time.Time // first day of the month, in New York
+	story *item     // nil until posted
+}
+
+// monthlyThreads returns the monthly threads from months ago to the next
+// month, with the stories of those already posted.
+func monthlyThreads(months int) ([]thread, error) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return nil, err
+	}
+	u, err := getUser(threadsUser)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("no user %s", threadsUser)
+	}
+	// Each month brings three threads, besides the odd other story.
+	n := min(len(u.Submitted), 4*(months+2))
+	items, err := getItems(u.Submitted[:n])
+	if err != nil {
+		return nil, err
+	}
+	posted := make(map[string]*item)
+	for i := range items {
+		m := threadTitle.FindStringSubmatch(items[i].PlainTitle())
+		if m == nil {
+			continue
+		}
+		month, err := time.ParseInLocation("January 2006", m[2]+" "+m[3], ny)
+		if err != nil {
+			continue
+		}
+		key := m[1] + month.Format(" 2006-01")
+		if posted[key] == nil {
+			posted[key] = &items[i]
+		}
+	}
+	now := time.Now().In(ny)
+	first := time.Date(now.Year(), now.Month()-time.Month(months), 1, 0, 0, 0, 0, ny)
+	var threads []thread
+	for month := first; !month.After(now.AddDate(0, 1, 0)); month = month.AddDate(0, 1, 0) {
+		for _, kind := range threadKinds {
+			threads = append(threads, thread{kind, month, posted[kind+month.Format(" 2006-01")]})
+		}
+	}
+	return threads, nil
+}
+
+// title returns the title the thread t has, or will have.
+func (t *thread) title() string {
+	return fmt.Sprintf("Ask HN: %s (%s)", t.kind, t.month.Format("January 2006"))
+}
+
+// start returns the time t was posted or, if it was not yet, when it is
+// expected to be: at threadsHour of the first weekday of the month.
+func (t *thread) start() time.Time {
+	if t.story != nil {
+		return t.story.Created()
+	}
+	d := t.month
+	for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		d = d.AddDate(0, 0, 1)
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), threadsHour, 0, 0, 0, d.Location())
+}
+
+// serveThreads serves an iCalendar feed of the monthly threads of the
+// last year and the next month, linking to those already posted, for
+// calendar apps to subscribe to.
+func serveThreads(w http.ResponseWriter, r *http.Request) {
+	threads, err := monthlyThreads(12)
+	if err != nil {
+		log.Print(err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if err := writeICal(w, threads); err != nil {
+		log.Print(err)
+	}
+}
+
+// writeICal writes threads to w as an iCalendar (RFC 5545) feed, an
+// event an hour long for each.
+func writeICal(w io.Writer, threads []thread) error {
+	var b strings.Builder
+	line := func(name, value string) {
+		l := name + ":" + value
+		// Fold lines longer than 75 octets, counting the space that
+		// starts continuation lines, without splitting characters.
+		for limit := 75; len(l) > limit; limit = 74 {
+			i := limit
+			for i > 0 && !utf8.RuneStart(l[i]) {
+				i--
+			}
+			b.WriteString(l[:i] + "\r\n ")
+			l = l[i:]
+		}
+		b.WriteString(l + "\r\n")
+	}
+	const stamp = "20060102T150405Z"
+	now := time.Now().UTC().Format(stamp)
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//news//Hacker News monthly threads//EN")
+	line("X-WR-CALNAME", "Hacker News monthly threads")
+	for _, t := range threads {
+		start := t.start().UTC()
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("%s-%s@news", slug(t.kind), t.month.Format("2006-01")))
+		line("DTSTAMP", now)
+		line("DTSTART", start.Format(stamp))
+		line("DTEND", start.Add(time.Hour).Format(stamp))
+		line("SUMMARY", icalText(t.title()))
+		if t.story != nil {
+			line("URL", itemURL(t.story.ID))
+			line("DESCRIPTION", icalText(fmt.Sprintf("%d comments: %s", t.story.Descendants, itemURL(t.story.ID))))
+		} else if start.After(time.Now()) {
+			line("DESCRIPTION", icalText("Not posted yet."))
+		} else {
+			line("DESCRIPTION", icalText("Not found among the submissions of "+threadsUser+"."))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// icalText escapes s as an iCalendar TEXT value.
+func icalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// timings records the HTTP requests made during a run, for -timings.
+var timings struct {
+	start     time.Time
+	mu        sync.Mutex
+	latencies []time.Duration // of the requests, until the response headers
+	errors    int
+	cacheHits atomic.Int64 // of the shared cache
+	retries   atomic.Int64
+	throttled atomic.Int64 // responses asking to retry later
+}
+
+// A timingTransport records the latency of the requests it makes, and,
+// with -debug, the connections they use.
+type timingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if *debug {
+		req = traceConns(req)
+	}
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	d := time.Since(start)
+	if *debug && err == nil {
+		countProto(resp)
+	}
+	timings.mu.Lock()
+	timings.latencies = append(timings.latencies, d)
+	if err != nil {
+		timings.errors++
+	}
+	timings.mu.Unlock()
+	return resp, err
+}
+
+// reportTimings prints a summary of the requests made to standard error,
+// if -timings or -debug is set. With -debug, it also reports how the
+// connections were used.
+func reportTimings() {
+	if !*showTimings && !*debug {
+		return
+	}
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+	wall := time.Since(timings.start)
+	lat := slices.Clone(timings.latencies)
+	slices.Sort(lat)
+	n := len(lat)
+	fmt.Fprintf(os.Stderr, "requests:   %d (%d failed, %d retries), %.1f/s\n", n, timings.errors, timings.retries.Load(), float64(n)/wall.Seconds())
+	if n > 0 {
+		fmt.Fprintf(os.Stderr, "latency:    p50 %v, p95 %v, max %v\n",
+			percentile(lat, 50), percentile(lat, 95), lat[n-1].Round(time.Microsecond))
+	}
+	fmt.Fprintf(os.Stderr, "throttled:  %d\n", timings.throttled.Load())
+	fmt.Fprintf(os.Stderr, "cache hits: %d\n", timings.cacheHits.Load())
+	if *debug {
+		reportConns()
+	}
+	fmt.Fprintf(os.Stderr, "wall time:  %v\n", wall.Round(time.Millisecond))
+}
+
+// percentile returns the p-th percentile of the sorted durations d, by
+// the nearest-rank method.
+func percentile(d []time.Duration, p int) time.Duration {
+	i := (len(d)*p + 99) / 100
+	return d[max(i-1, 0)].Round(time.Microsecond)
+}
+
+// A fetchMeta tells, with -verbose-meta, how an item was fetched: how long
+// it took, in milliseconds, whether it came from the shared cache of the
+// serve command, and how many times the request was retried.
+type fetchMeta struct {
+	FetchMS  float64
+	CacheHit bool
+	Retries  int
+}
+
+// fetchMetaKey is the context key of the fetchMeta of a request, which
+// retryTransport counts retries in.
+type fetchMetaKey struct{}
+
+// countRetry counts a retry of req in its fetchMeta, if it has one.
+func countRetry(req *http.Request) {
+	if m, ok := req.Context().Value(fetchMetaKey{}).(*fetchMeta); ok {
+		m.Retries++
+	}
+}
+
+// fetched records that the item was fetched rather than taken from the
+// shared cache. A nil m records nothing.
+func (m *fetchMeta) fetched() {
+	if m != nil {
+		m.CacheHit = false
+	}
+}
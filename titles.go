@@ -0,0 +1,147 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// With -title-only, search matches the pattern against titles alone, which
+// it gets without fetching whole items: the API serves each field of an
+// item on its own, so only the title is fetched, and only the stories that
+// match are fetched in full. The titles are also kept in a cache file, as
+// they seldom change, so later runs only fetch those of new stories.
+
+// maxCachedTitles bounds the size of the title cache. The newest stories
+// are kept, since story lists hold mostly recent ones.
+const maxCachedTitles = 20000
+
+// titlesMu serializes the use of the title cache by concurrent searches.
+var titlesMu sync.Mutex
+
+// titleCacheFile returns the path of the title cache.
+func titleCacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "news-titles.json"
+	}
+	return filepath.Join(dir, "news", "titles.json")
+}
+
+// matchTitles returns the stories among ids whose titles match re, in the
+// same order.
+func matchTitles(ctx context.Context, ids []int, re *pattern) ([]int, error) {
+	titlesMu.Lock()
+	defer titlesMu.Unlock()
+	titles := loadTitles(titleCacheFile())
+	type result struct {
+		id    int
+		title string
+		err   error
+	}
+	var missing []int
+	for _, id := range ids {
+		if _, ok := titles[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	noteFetches(len(missing))
+	c := make(chan result, len(missing))
+	for _, id := range missing {
+		go func() {
+			title, err := getTitle(ctx, id)
+			c <- result{id, title, err}
+		}()
+	}
+	for range missing {
+		r := <-c
+		if r.err != nil {
+			noteError("title", r.id, r.err)
+			continue
+		}
+		titles[r.id] = r.title
+	}
+	if err := saveTitles(titleCacheFile(), titles); err != nil {
+		return nil, err
+	}
+	var matched []int
+	for _, id := range ids {
+		// Titles hold entities such as &amp;, as in whole items.
+		it := item{Title: titles[id]}
+		if re.matchText(normalize(it.PlainTitle(), *fold)) {
+			matched = append(matched, id)
+		}
+	}
+	return matched, nil
+}
+
+// getTitle fetches the title of the item id, which is empty for items
+// without one, such as comments.
+func getTitle(ctx context.Context, id int) (string, error) {
+	url := basePath + "/item/" + strconv.Itoa(id) + "/title.json"
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("fetch: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch: %w", err)
+	}
+	defer resp.Body.Close()
+	var title *string
+	if err := json.NewDecoder(resp.Body).Decode(&title); err != nil {
+		return "", fmt.Errorf("fetch: %w", err)
+	}
+	if title == nil {
+		return "", nil
+	}
+	return *title, nil
+}
+
+// loadTitles reads the title cache. A missing or unreadable cache is
+// taken to be empty, since it can be rebuilt.
+func loadTitles(file string) map[int]string {
+	titles := make(map[int]string)
+	data, err := os.ReadFile(file)
+	if err == nil {
+		json.Unmarshal(data, &titles)
+	}
+	return titles
+}
+
+// saveTitles writes the title cache, keeping only the newest stories.
+func saveTitles(file string, titles map[int]string) error {
+	if len(titles) > maxCachedTitles {
+		ids := make([]int, 0, len(titles))
+		for id := range titles {
+			ids = append(ids, id)
+		}
+		slices.SortFunc(ids, func(a, b int) int { return cmp.Compare(b, a) })
+		for _, id := range ids[maxCachedTitles:] {
+			delete(titles, id)
+		}
+	}
+	data, err := json.Marshal(titles)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// A sample is the state of a tracked story at some point in time.
+type sample struct {
+	ID       int       `json:"id"`
+	Time     time.Time `json:"time"`
+	Score    int       `json:"score"`
+	Comments int       `json:"comments"`
+	Rank     int       `json:"rank,omitempty"` // in the top stories list
+}
+
+// runTrack implements the track command. It polls the stories whose IDs
+// are in args every -interval, appends their score and comment count to
+// the -store file and prints how they changed.
+func runTrack(args []string) {
+	if len(args) == 0 {
+		fatal(fmt.Errorf("track: no story IDs"))
+	}
+	var ids []int
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			fatal(fmt.Errorf("track: invalid story ID %q", arg))
+		}
+		ids = append(ids, id)
+	}
+	// Changes are reported relative to the last recorded sample, so they
+	// carry on from earlier runs.
+	last := make(map[int]sample)
+	scores := make(map[int][]int) // score history, for the sparklines
+	for _, id := range ids {
+		samples, err := loadSamples(*store, id)
+		if err != nil {
+			fatal(err)
+		}
+		if len(samples) > 0 {
+			last[id] = samples[len(samples)-1]
+		}
+		for _, s := range samples {
+			scores[id] = append(scores[id], s.Score)
+		}
+	}
+	for {
+		c := make(chan fetchResult, len(ids))
+		for _, id := range ids {
+			go fetch(context.Background(), id, c)
+		}
+		ranks := make(map[int]int)
+		if fp, err := getFrontPage(false); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			ranks = fp.ranks
+		}
+		now := time.Now()
+		clock := now.In(location).Format("15:04")
+		var samples []sample
+		items := make(map[int]item)
+		for range ids {
+			r := <-c
+			if r.err != nil {
+				// A failed poll is not worth stopping for; the next
+				// one will likely succeed.
+				fmt.Fprintln(os.Stderr, r.err)
+				continue
+			}
+			items[r.ID] = r.item
+			samples = append(samples, sample{ID: r.ID, Time: now, Score: r.Score, Comments: r.Descendants, Rank: ranks[r.ID]})
+		}
+		if err := appendSamples(*store, samples); err != nil {
+			fatal(err)
+		}
+		for _, id := range ids {
+			it, ok := items[id]
+			if !ok {
+				continue
+			}
+			s := sample{ID: id, Score: it.Score, Comments: it.Descendants, Rank: ranks[id]}
+			prev, seen := last[id]
+			if !seen {
+				prev = s
+			}
+			scores[id] = append(scores[id], s.Score)
+			if n := len(scores[id]); n > sparklineWidth {
+				scores[id] = scores[id][n-sparklineWidth:]
+			}
+			fmt.Printf("%s  %d  %d points (%+d) %s  %d comments (%+d)  rank %s  %s\n",
+				clock, id,
+				s.Score, s.Score-prev.Score, sparkline(scores[id]),
+				s.Comments, s.Comments-prev.Comments,
+				formatRank(s.Rank, false), it.PlainTitle())
+			switch was, is := onFrontPage(prev.Rank), onFrontPage(s.Rank); {
+			case seen && !was && is:
+				fmt.Printf("%s  %d  entered the front page at rank %d\n", clock, id, s.Rank)
+			case seen && was && !is:
+				fmt.Printf("%s  %d  left the front page\n", clock, id)
+			}
+			last[id] = s
+		}
+		if *interval <= 0 {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// onFrontPage reports whether rank is a position on the front page.
+func onFrontPage(rank int) bool {
+	return rank > 0 && rank <= frontPageSize
+}
+
+// defaultStore returns the file where tracked samples are kept by default.
+func defaultStore() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "news-track.jsonl"
+	}
+	return filepath.Join(dir, "news", "track.jsonl")
+}
+
+// appendSamples adds samples to the store file, one JSON object per line,
+// creating the file if needed.
+func appendSamples(file string, samples []sample) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadSamples returns the samples recorded in the store file for the
+// story id, oldest first. A missing file holds no samples.
+func loadSamples(file string, id int) ([]sample, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var samples []sample
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var s sample
+		if err := dec.Decode(&s); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if s.ID == id {
+			samples = append(samples, s)
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// setupTransport tunes the default transport, which every HTTP client
+// uses, with the connection flags, makes it cache host name lookups and
+// retry failed requests, and, with -timings, time them.
+// Fetching hundreds of items at once is dominated by how connections are
+// made, kept and reused.
+func setupTransport() error {
+	tc, err := newTLSConfig()
+	if err != nil {
+		return err
+	}
+	tlsConfig = tc
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = tc
+	family := "ip"
+	switch {
+	case *ip4:
+		family = "ip4"
+	case *ip6:
+		family = "ip6"
+	}
+	t.DialContext = newDNSCache(family).dialContext
+	t.MaxIdleConns = max(t.MaxIdleConns, *maxIdleConns)
+	t.MaxIdleConnsPerHost = *maxIdleConns
+	t.IdleConnTimeout = *idleTimeout
+	if *http1 {
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	var rt http.RoundTripper = t
+	if *showTimings || *debug {
+		timings.start = time.Now()
+		rt = &timingTransport{base: rt}
+	}
+	retrier = &retryTransport{base: rt}
+	http.DefaultTransport = retrier
+	return nil
+}
+
+// tlsConfig is the TLS configuration of all connections, as set by the
+// flags and the configuration file. Clone it before setting ServerName.
+var tlsConfig *tls.Config
+
+// newTLSConfig returns the TLS configuration given by -ca-file and
+// -insecure-skip-verify, or their counterparts in the configuration file.
+func newTLSConfig() (*tls.Config, error) {
+	c := new(tls.Config)
+	file := cmp.Or(*caFile, cfg().TLS.CAFile)
+	if file != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no PEM certificates", file)
+		}
+		c.RootCAs = pool
+	}
+	if *insecure || cfg().TLS.InsecureSkipVerify {
+		log.Print("warning: TLS certificates are not verified")
+		c.InsecureSkipVerify = true
+	}
+	return c, nil
+}
+
+// connStats counts the connections used by requests, for -debug.
+var connStats struct {
+	new, reused, idle atomic.Int64
+	idleTime          atomic.Int64 // total time reused connections were idle, in nanoseconds
+	protos            [2]atomic.Int64
+}
+
+// traceConns returns req with a trace that counts its connection.
+func traceConns(req *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				connStats.new.Add(1)
+				return
+			}
+			connStats.reused.Add(1)
+			if info.WasIdle {
+				connStats.idle.Add(1)
+				connStats.idleTime.Add(int64(info.IdleTime))
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// countProto counts the protocol of resp.
+func countProto(resp *http.Response) {
+	if resp.ProtoMajor >= 2 {
+		connStats.protos[1].Add(1)
+	} else {
+		connStats.protos[0].Add(1)
+	}
+}
+
+// reportConns prints the connection counts to standard error.
+func reportConns() {
+	fmt.Fprintf(os.Stderr, "conns:      %d new, %d reused (%d idle", connStats.new.Load(), connStats.reused.Load(), connStats.idle.Load())
+	if n := connStats.idle.Load(); n > 0 {
+		fmt.Fprintf(os.Stderr, ", for %v on average", (time.Duration(connStats.idleTime.Load()) / time.Duration(n)).Round(time.Millisecond))
+	}
+	fmt.Fprintf(os.Stderr, ")\n")
+	fmt.Fprintf(os.Stderr, "protocols:  %d HTTP/1.x, %d HTTP/2\n", connStats.protos[0].Load(), connStats.protos[1].Load())
+}
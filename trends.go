@@ -0,0 +1,119 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// runTrends implements the trends command. It prints the most frequent
+// terms and pairs of consecutive terms in the titles of the selected list,
+// or of the stories matching the pattern in args, if any.
+func runTrends(args []string) {
+	var re *pattern
+	if len(args) > 0 {
+		var err error
+		if re, err = compile(args[0]); err != nil {
+			fatal(err)
+		}
+	}
+	r, err := search(listName(), re)
+	if err != nil {
+		fatal(err)
+	}
+	terms, bigrams := trends(r.Items)
+	if err := printTrends(os.Stdout, terms, bigrams); err != nil {
+		fatal(err)
+	}
+}
+
+// trends counts the terms and bigrams in the titles of items. Stop words
+// are dropped and never form part of a bigram. Each title counts at most
+// once for a given term or bigram.
+func trends(items []item) (terms, bigrams []count) {
+	termCount := make(map[string]int)
+	bigramCount := make(map[string]int)
+	for i := range items {
+		seen := make(map[string]bool)
+		words := tokenize(items[i].PlainTitle())
+		for j, w := range words {
+			if stopWords[w] {
+				continue
+			}
+			if !seen[w] {
+				termCount[w]++
+				seen[w] = true
+			}
+			if j+1 < len(words) && !stopWords[words[j+1]] {
+				bg := w + " " + words[j+1]
+				if !seen[bg] {
+					bigramCount[bg]++
+					seen[bg] = true
+				}
+			}
+		}
+	}
+	// A bigram seen once says nothing about a trend.
+	for bg, n := range bigramCount {
+		if n < 2 {
+			delete(bigramCount, bg)
+		}
+	}
+	return mostCommon(termCount, *topCount), mostCommon(bigramCount, *topCount)
+}
+
+// tokenize splits s into lower-case words. Letters, digits and the
+// characters in "+#." inside a word are kept, so that "C++", "C#" and
+// "Node.js" survive; a trailing "'s" is removed.
+func tokenize(s string) []string {
+	var words []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("+#.'’", r)
+	}) {
+		f = strings.TrimSuffix(strings.TrimSuffix(f, "'s"), "’s")
+		f = strings.Trim(f, ".'’")
+		if len([]rune(f)) < 2 && f != "c" && f != "r" {
+			continue
+		}
+		words = append(words, f)
+	}
+	return words
+}
+
+func printTrends(w io.Writer, terms, bigrams []count) error {
+	var b strings.Builder
+	writeCounts(&b, "terms", terms)
+	writeCounts(&b, "bigrams", bigrams)
+	_, err := io.WriteString(w, strings.TrimPrefix(b.String(), "\n"))
+	return err
+}
+
+// stopWords are the English words too common to say anything about a
+// title, plus the HN prefixes.
+var stopWords = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(`
+		a about above after again against all am an and any are aren't as at
+		be because been before being below between both but by can can't
+		cannot could couldn't did didn't do does doesn't doing don't down
+		during each few for from further get gets got had hadn't has hasn't
+		have haven't having he her here hers herself him himself his how i
+		if in into is isn't it it's its itself just let's like me more most
+		my myself new no nor not now of off on once only or other our ours
+		ourselves out over own same she should shouldn't so some such than
+		that that's the their theirs them themselves then there these they
+		this those through to too under until up us use using very via vs
+		was wasn't we were weren't what when where which while who whom why
+		will with without won't would wouldn't you your yours yourself
+		yourselves
+		ask show tell hn launch yc pdf video
+	`) {
+		m[w] = true
+	}
+	return m
+}()
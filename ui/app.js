@@ -0,0 +1,128 @@
+// Run the search in the form through /events, adding the stories to the
+// table as they arrive, newest first; later matches are highlighted.
+// The API key of the page, if any, is passed on to /events.
+(() => {
+	const hn = "https://news.ycombinator.com";
+	const form = document.getElementById("search");
+	const saved = document.getElementById("saved");
+	const status = document.getElementById("status");
+	const table = document.getElementById("stories");
+	const tbody = table.tBodies[0];
+	const key = new URLSearchParams(location.search).get("key");
+	let source = null;
+
+	const link = (href, text) => {
+		const a = document.createElement("a");
+		a.href = href;
+		a.textContent = text;
+		return a;
+	};
+	const cell = (row, ...children) => {
+		const td = row.insertCell();
+		td.append(...children);
+		return td;
+	};
+	const plain = (html) => new DOMParser().parseFromString(html, "text/html").body.textContent;
+
+	const row = (it) => {
+		const tr = document.createElement("tr");
+		tr.dataset.time = it.Time;
+		cell(tr, String(it.Score)).className = "num";
+		cell(tr, link(hn + "/item?id=" + it.ID, String(it.Descendants))).className = "num";
+		cell(tr, link(hn + "/user?id=" + encodeURIComponent(it.By), it.By));
+		const time = document.createElement("time");
+		const created = new Date(it.Time * 1000);
+		time.dateTime = created.toISOString();
+		time.textContent = created.toLocaleString();
+		cell(tr, time);
+		const title = cell(tr);
+		if (it.URL) {
+			const host = document.createElement("span");
+			host.className = "host";
+			host.textContent = " (" + new URL(it.URL).hostname + ")";
+			title.append(link(it.URL, plain(it.Title)), host);
+		} else {
+			title.append(link(hn + "/item?id=" + it.ID, plain(it.Title)));
+		}
+		for (const name of it.Labels || []) {
+			const label = document.createElement("span");
+			label.className = "label";
+			label.textContent = name;
+			title.append(" ", label);
+		}
+		return tr;
+	};
+	const count = () => {
+		const n = tbody.rows.length;
+		status.className = "";
+		status.textContent = n === 1 ? "1 story" : n + " stories";
+	};
+
+	const run = () => {
+		if (source) {
+			source.close();
+		}
+		tbody.replaceChildren();
+		table.hidden = true;
+		status.className = "";
+		status.textContent = "Searching…";
+		const params = new URLSearchParams(new FormData(form));
+		if (key) {
+			params.set("key", key);
+		}
+		history.replaceState(null, "", "?" + params);
+		const seen = new Set();
+		const opened = Date.now();
+		source = new EventSource("events?" + params);
+		source.addEventListener("item", (e) => {
+			const it = JSON.parse(e.data);
+			if (seen.has(it.ID)) {
+				return; // sent again after reconnecting
+			}
+			seen.add(it.ID);
+			const tr = row(it);
+			if (Date.now() - opened > 10000) {
+				tr.className = "fresh";
+			}
+			const next = Array.from(tbody.rows).find((r) => Number(r.dataset.time) < it.Time);
+			tbody.insertBefore(tr, next || null);
+			table.hidden = false;
+			count();
+		});
+		source.addEventListener("error", (e) => {
+			status.className = "error";
+			if (e.data) {
+				status.textContent = JSON.parse(e.data);
+			} else if (source.readyState === EventSource.CLOSED) {
+				status.textContent = "The search failed; check the pattern.";
+			} else {
+				status.textContent = "Reconnecting…";
+			}
+		});
+	};
+
+	form.addEventListener("submit", (e) => {
+		e.preventDefault();
+		run();
+	});
+	if (saved) {
+		saved.addEventListener("change", () => {
+			const opt = saved.selectedOptions[0];
+			if (!opt.value) {
+				return;
+			}
+			form.elements.q.value = opt.value;
+			form.elements.list.value = opt.dataset.list || "new";
+			run();
+		});
+	}
+	const initial = new URLSearchParams(location.search);
+	for (const name of ["q", "list", "points", "comments"]) {
+		if (initial.has(name)) {
+			form.elements[name].value = initial.get(name);
+		}
+	}
+	if (initial.has("q")) {
+		run();
+	}
+})();
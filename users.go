@@ -0,0 +1,169 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxUserRequests bounds the users fetched at a time by -enrich-users.
+const maxUserRequests = 8
+
+// An author is what -enrich-users tells about the submitter of a story.
+type author struct {
+	Karma   int
+	Created int64 // creation date of the account, in Unix Time
+	AgeDays int   // days since the account was created
+}
+
+// addAuthors sets the Author field of the items of r to what the API has
+// on their submitters, fetching each of them once. Failures are noted for
+// -error-summary and leave the field nil, as do deleted accounts.
+func addAuthors(r *searchResult) {
+	byName := make(map[string][]*item)
+	for i := range r.Items {
+		it := &r.Items[i]
+		if it.By != "" {
+			byName[it.By] = append(byName[it.By], it)
+		}
+	}
+	sem := make(chan struct{}, maxUserRequests)
+	var wg sync.WaitGroup
+	for name, items := range byName {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			u, err := getUser(name)
+			if err != nil {
+				noteError("user", items[0].ID, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			if u == nil {
+				return
+			}
+			a := &author{
+				Karma:   u.Karma,
+				Created: int64(u.Created),
+				AgeDays: int(time.Since(time.Unix(int64(u.Created), 0)) / (24 * time.Hour)),
+			}
+			for _, it := range items {
+				it.Author = a
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// filterKarma returns the items whose submitters have at least n karma,
+// or are unknown, having no Author.
+func filterKarma(items []item, n int) []item {
+	return slices.DeleteFunc(items, func(it item) bool {
+		return it.Author != nil && it.Author.Karma < n
+	})
+}
+
+// Joined returns the creation time of the account.
+func (a *author) Joined() time.Time {
+	return time.Unix(a.Created, 0)
+}
+
+// String describes a as the -text output does, such as "4321 karma,
+// joined 3y ago".
+func (a *author) String() string {
+	return fmt.Sprintf("%d karma, joined %s", a.Karma, relativeTime(time.Since(a.Joined())))
+}
+
+// runUser runs the user command, which prints the profile of the user
+// args[0], with their karma, the date they joined and their about text,
+// followed by their -n most recent submissions and comments. The items
+// can also be written with -out, -jq, -raw or -format, which leave out
+// the profile.
+func runUser(args []string) {
+	if len(args) != 1 {
+		fatal(fmt.Errorf("user: want a user name"))
+	}
+	name := args[0]
+	u, err := getUser(name)
+	if err != nil {
+		fatal(err)
+	}
+	if u == nil {
+		fatal(fmt.Errorf("user: no such user %s", name))
+	}
+	items, err := getItems(u.Submitted[:min(len(u.Submitted), *topCount)])
+	if err != nil {
+		fatal(err)
+	}
+	for i := range items {
+		// Comments have no title, but their text follows in the table.
+		if it := &items[i]; it.Type == "comment" {
+			it.Title = fmt.Sprintf("Comment on %d", it.Parent)
+		}
+	}
+	if len(outputs) == 0 && jqProgram == nil && !*rawOut && lineFormat == nil {
+		if err := printUser(os.Stdout, u); err != nil {
+			fatal(err)
+		}
+	}
+	r := &searchResult{Total: len(items), Items: items}
+	if err := writeItems(savedSearch{Name: "user " + name}, r); err != nil {
+		fatal(err)
+	}
+}
+
+// writeItems writes the items of r, listed by the command that s names,
+// as -out, -jq, -raw or -format say, or else as a plain-text table, whose
+// columns -columns may choose.
+func writeItems(s savedSearch, r *searchResult) error {
+	switch {
+	case len(outputs) > 0:
+		return writeOutputs(outputs, s, r)
+	case jqProgram != nil:
+		return writeJQ(os.Stdout, jqProgram, r)
+	case *rawOut:
+		return writeRaw(os.Stdout, r)
+	case lineFormat != nil:
+		for i := range r.Items {
+			if err := writeFormatted(os.Stdout, &r.Items[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case columns != nil:
+		return printColumns(os.Stdout, r)
+	}
+	return printText(os.Stdout, r)
+}
+
+// printUser writes the profile of u to w as plain text.
+func printUser(w io.Writer, u *user) error {
+	created := time.Unix(int64(u.Created), 0)
+	var b strings.Builder
+	fmt.Fprintf(&b, "user:      %s\n", u.ID)
+	fmt.Fprintf(&b, "karma:     %d\n", u.Karma)
+	fmt.Fprintf(&b, "joined:    %s (%s)\n", created.In(location).Format("2006-01-02"), relativeTime(time.Since(created)))
+	fmt.Fprintf(&b, "submitted: %d\n", len(u.Submitted))
+	if u.About != "" {
+		const indent = "    "
+		b.WriteString("about:\n")
+		for _, line := range strings.Split(htmlToText(u.About, maxTextWidth-len(indent)), "\n") {
+			if line != "" {
+				line = indent + line
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
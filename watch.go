@@ -0,0 +1,139 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// defaultWatchInterval is how often the watchlist runs if it has no
+// schedule.
+const defaultWatchInterval = 5 * time.Minute
+
+// A watchlist is a set of patterns, each with its own filters and sinks,
+// that the serve command matches against a single fetch of a story list,
+// such as
+//
+//	"watchlist": {
+//		"list": "new",
+//		"every": "10m",
+//		"entries": [
+//			{"name": "rust", "pattern": "(?i)\\brust\\b", "min_score": 20, "save_to": ["pinboard"]},
+//			{"name": "mycompany", "pattern": "(?i)mycompany", "save_to": ["mqtt", "nats"]}
+//		]
+//	}
+type watchlist struct {
+	List    string       `json:"list,omitempty"` // new (the default), top or best
+	Every   string       `json:"every,omitempty"`
+	Cron    string       `json:"cron,omitempty"`
+	Entries []watchEntry `json:"entries"`
+}
+
+// A watchEntry is an entry of the watchlist.
+type watchEntry struct {
+	Name        string   `json:"name"`
+	Pattern     string   `json:"pattern"`
+	MinScore    int      `json:"min_score,omitempty"`
+	MinComments int      `json:"min_comments,omitempty"`
+	Lang        []string `json:"lang,omitempty"` // ISO 639-1 codes, as with -lang
+	SaveTo      []string `json:"save_to"`
+}
+
+// check reports whether w is valid.
+func (w *watchlist) check() error {
+	switch w.List {
+	case "", "new", "top", "best":
+	default:
+		return fmt.Errorf("invalid list %q", w.List)
+	}
+	if _, err := parseSchedule(w.Every, w.Cron); err != nil {
+		return err
+	}
+	for _, e := range w.Entries {
+		if e.Name == "" || e.Pattern == "" {
+			return fmt.Errorf("every entry needs a name and a pattern")
+		}
+		if _, err := compile(e.Pattern); err != nil {
+			return fmt.Errorf("entry %q: %v", e.Name, err)
+		}
+	}
+	return nil
+}
+
+// filter returns the items that match e.
+func (e *watchEntry) filter(re *pattern, items []item) []item {
+	var kept []item
+	for _, it := range items {
+		if it.Score >= e.MinScore && it.Descendants >= e.MinComments && it.matches(re) {
+			kept = append(kept, it)
+		}
+	}
+	if len(e.Lang) > 0 {
+		kept = filterLanguage(kept, e.Lang)
+	}
+	return kept
+}
+
+// runWatchlist runs the watchlist w on its schedule. Each run fetches the
+// stories of the list once, matches every entry against them and saves
+// the matches not seen in earlier runs to the sinks of the entry. Stories
+// that match several entries are labeled with the names of all of them.
+// seen holds the stories already saved for each entry. It returns when ctx
+// is done.
+func runWatchlist(ctx context.Context, w watchlist, seen []map[int]bool) {
+	list := w.List
+	if list == "" {
+		list = "new"
+	}
+	sched, _ := parseSchedule(w.Every, w.Cron)
+	if sched == nil {
+		sched = every(defaultWatchInterval)
+	}
+	res := make([]*pattern, len(w.Entries))
+	for i, e := range w.Entries {
+		res[i], _ = compile(e.Pattern) // checked when loading
+	}
+	for {
+		t := sched.next(time.Now())
+		if t.IsZero() {
+			log.Print("watchlist: schedule never runs")
+			return
+		}
+		if !sleepUntil(ctx, t) {
+			return
+		}
+		all, err := search(list, nil)
+		if err != nil {
+			log.Printf("watchlist: %v", err)
+			continue
+		}
+		matches := make([][]item, len(w.Entries))
+		hits := make(map[int][]string) // names of the entries each story matched
+		for i, e := range w.Entries {
+			matches[i] = e.filter(res[i], all.Items)
+			for _, it := range matches[i] {
+				hits[it.ID] = append(hits[it.ID], e.Name)
+			}
+		}
+		for i, e := range w.Entries {
+			fresh := &searchResult{Items: unseen(seen[i], matches[i]), re: res[i]}
+			for j := range fresh.Items {
+				if names := hits[fresh.Items[j].ID]; len(names) > 1 {
+					fresh.Items[j].Labels = names
+				}
+			}
+			fresh.Total = len(fresh.Items)
+			log.Printf("watchlist: %s: %d matches, %d new", e.Name, len(matches[i]), fresh.Total)
+			if len(e.SaveTo) > 0 && fresh.Total > 0 {
+				if err := saveMatches(e.SaveTo, e.Name, fresh); err != nil {
+					log.Printf("watchlist: %s: %v", e.Name, err)
+				}
+			}
+		}
+	}
+}
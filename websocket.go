@@ -0,0 +1,224 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serveWebSocket sends the matches of the live query in the URL, like
+// serveEvents, over a WebSocket, for the clients that prefer one to
+// server-sent events. Each message is a JSON object, either
+//
+//	{"type": "item", "item": {...}}
+//
+// or, when a search fails,
+//
+//	{"type": "error", "error": "..."}
+//
+// The messages of the client are ignored, besides those closing the
+// connection and pings.
+func serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	q, err := parseLiveQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ws, err := upgrade(w, r)
+	if err != nil {
+		log.Printf("ws: %v", err)
+		return
+	}
+	defer ws.conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		ws.readLoop()
+		cancel()
+	}()
+	send := func(v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return ws.write(wsText, data)
+	}
+	q.watch(ctx, func(it item) error {
+		return send(struct {
+			Type string `json:"type"`
+			Item item   `json:"item"`
+		}{"item", it})
+	}, func(err error) error {
+		log.Printf("ws: %v", err)
+		return send(map[string]string{"type": "error", "error": err.Error()})
+	})
+	// 1001 is going away, as the server does when shutting down.
+	ws.write(wsClose, binary.BigEndian.AppendUint16(nil, 1001))
+}
+
+// The opcodes of WebSocket frames.
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// wsMaxPayload is the size of the largest frame accepted from clients,
+// which have nothing to send but control frames.
+const wsMaxPayload = 1 << 16
+
+// A wsConn is the server end of a WebSocket connection (RFC 6455).
+type wsConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	mu     sync.Mutex // held while writing a frame
+	closed bool       // a close frame was sent
+}
+
+// upgrade answers the opening handshake of a WebSocket in r, taking over
+// its connection.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "not a WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("not a WebSocket handshake")
+	}
+	if v := r.Header.Get("Sec-WebSocket-Version"); v != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("unsupported WebSocket version %q", v)
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return &wsConn{conn: conn, r: rw.Reader}, nil
+}
+
+// headerHas reports whether the comma-separated field name of h has the
+// token, in any case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for t := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// write writes a frame with the opcode and payload. Nothing is written
+// after a close frame.
+func (c *wsConn) write(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	c.closed = op == wsClose
+	hdr := []byte{0x80 | op} // FIN
+	switch n := len(payload); {
+	case n < 126:
+		hdr = append(hdr, byte(n))
+	case n <= 0xFFFF:
+		hdr = binary.BigEndian.AppendUint16(append(hdr, 126), uint16(n))
+	default:
+		hdr = binary.BigEndian.AppendUint64(append(hdr, 127), uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	_, err := c.conn.Write(append(hdr, payload...))
+	return err
+}
+
+// readLoop reads the frames of the client, answering pings, until the
+// connection is closed.
+func (c *wsConn) readLoop() {
+	for {
+		op, payload, err := c.read()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("ws: %v", err)
+			}
+			return
+		}
+		switch op {
+		case wsPing:
+			c.write(wsPong, payload)
+		case wsClose:
+			c.write(wsClose, payload[:min(len(payload), 2)])
+			return
+		}
+	}
+}
+
+// read reads a frame of the client.
+func (c *wsConn) read() (op byte, payload []byte, err error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	op = hdr[0] & 0x0F
+	if hdr[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked frame from client")
+	}
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if n > wsMaxPayload {
+		return 0, nil, fmt.Errorf("frame of %d bytes is too large", n)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
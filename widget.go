@@ -0,0 +1,89 @@
+// Copyright 2025 Francisco Oliveto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// runWidget implements the widget command, which prints an HTML snippet
+// to embed in a web page, showing the latest stories matching the pattern
+// and adding new ones as they appear. The stories come from the /events
+// of the serve command at -server, which must allow the origin of the
+// page with -cors-origins. An API key, if needed, goes in the key
+// parameter of -server; mind that anyone can read it in the page.
+func runWidget(args []string) {
+	if len(args) != 1 {
+		fatal(fmt.Errorf("usage: news widget PATTERN"))
+	}
+	if _, err := compile(args[0]); err != nil {
+		fatal(err)
+	}
+	server, err := url.Parse(*serverURL)
+	if err != nil || server.Host == "" {
+		fatal(fmt.Errorf("invalid -server %q", *serverURL))
+	}
+	params := server.Query()
+	params.Set("q", args[0])
+	params.Set("list", listName())
+	server.Path = strings.TrimSuffix(server.Path, "/") + "/events"
+	server.RawQuery = params.Encode()
+	err = widgetTemplate.Execute(os.Stdout, map[string]any{
+		"ID":      "news-" + rand.Text()[:8],
+		"Pattern": args[0],
+		"Events":  server.String(),
+		"Count":   *topCount,
+	})
+	if err != nil {
+		fatal(err)
+	}
+}
+
+var widgetTemplate = template.Must(template.New("widget").Parse(`<div id="{{.ID}}" class="news-widget" aria-label="Hacker News stories matching {{.Pattern}}">
+<ul></ul>
+</div>
+<style>
+.news-widget ul { list-style: none; margin: 0; padding: 0; }
+.news-widget li { margin: 0 0 .5em; }
+.news-widget small { color: #828282; }
+</style>
+<script>
+(() => {
+	const list = document.querySelector("#{{.ID}} ul");
+	const count = {{.Count}};
+	const seen = new Set();
+	const plain = (html) => new DOMParser().parseFromString(html, "text/html").body.textContent;
+	const events = new EventSource({{.Events}});
+	events.addEventListener("item", (e) => {
+		const it = JSON.parse(e.data);
+		if (seen.has(it.ID)) {
+			return;
+		}
+		seen.add(it.ID);
+		const li = document.createElement("li");
+		li.dataset.time = it.Time;
+		const a = document.createElement("a");
+		a.href = it.URL || "https://news.ycombinator.com/item?id=" + it.ID;
+		a.textContent = plain(it.Title);
+		const meta = document.createElement("small");
+		const comments = document.createElement("a");
+		comments.href = "https://news.ycombinator.com/item?id=" + it.ID;
+		comments.textContent = it.Descendants + " comments";
+		meta.append(" " + it.Score + " points, ", comments);
+		li.append(a, meta);
+		const next = Array.from(list.children).find((c) => Number(c.dataset.time) < it.Time);
+		list.insertBefore(li, next || null);
+		while (list.children.length > count) {
+			list.lastElementChild.remove();
+		}
+	});
+})();
+</script>
+`))